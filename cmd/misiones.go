@@ -4,9 +4,12 @@
 package main
 
 import (
+	"io"
 	"os"
 	"fmt"
 	"flag"
+	"strconv"
+	"strings"
 	"path/filepath"
 
 	"potano.misiones/sexp"
@@ -16,21 +19,174 @@ import (
 
 func main() {
 	sourceDir := "."
-	var generateFile, measureName string
-	var upToDistance float64
-	var checkRoutes, asMiles, relaxRouteCheck bool
+	var generateFile, generateFormat, globalName, pointsEncoding, simplify string
+	var geojsonFile, kmlFile, gpxFile, gpxItems, gpxAs string
+	var measureName, areaName, areaUnit, earthModel, fromPlace, toPlace string
+	var travelName, travelRate, matrixPlaces, matrixFormat, findRouteName, findWithin string
+	var crossingCheck, explainName, alternatives, atYear, betweenYears string
+	var upToDistance, snapTolerance float64
+	var checkRoutes, asMiles, relaxRouteCheck, splitLayers, snapMerge, fixThreading bool
 
-	flag.StringVar(&sourceDir, "d", ".", "directory containing .sexp files")
+	flag.StringVar(&sourceDir, "d", ".", "directory containing .sexp, .geojson, and .gpx files")
 	flag.StringVar(&generateFile, "g", "", "name of target Javascript file")
+	flag.StringVar(&generateFormat, "g-format", "",
+		"form of -g output: global, json, or module (default: from file extension)")
+	flag.StringVar(&globalName, "global", "allData",
+		"name of global variable assigned by -g output in global form")
+	flag.StringVar(&pointsEncoding, "points", "fixed",
+		"encoding of points in -g output: fixed, delta, or polyline6")
+	flag.StringVar(&simplify, "simplify", "",
+		"comma-separated tolerances in meters for simplified paths and polygons in -g output")
+	flag.BoolVar(&splitLayers, "split", false,
+		"split -g output into an index file and one file per layer")
+	flag.StringVar(&geojsonFile, "geojson", "", "name of target GeoJSON file")
+	flag.StringVar(&kmlFile, "kml", "", "name of target KML file (zipped if name ends in .kmz)")
+	flag.StringVar(&gpxFile, "gpx", "", "name of target GPX file")
+	flag.StringVar(&gpxItems, "gpx-items", "",
+		"comma-separated names of routes, segments, or paths to write to GPX file")
+	flag.StringVar(&gpxAs, "gpx-as", "trk", "write GPX items as 'trk' (tracks) or 'rte' (routes)")
 	flag.StringVar(&measureName, "m", "", "name of path or route to measure")
+	flag.StringVar(&areaName, "area", "",
+		"name of polygon, rectangle, circle, or feature whose area to measure")
+	flag.StringVar(&areaUnit, "area-unit", "meters",
+		"unit of area: acres or a length unit (squared) such as meters")
+	flag.StringVar(&earthModel, "earth-model", "",
+		"earth model for measurements: sphere[,radius], wgs84, or " +
+		"ellipsoid,semi-major-axis,inverse-flattening (overrides config)")
 	flag.Float64Var(&upToDistance, "u", 0.0,
 		"measure path only up to distance; report coordinates")
+	flag.StringVar(&fromPlace, "from", "",
+		"with -m, measure from waypoint, latitude,longitude pair, or distance, " +
+			"alone with -u to count the distance from it; " +
+			"with -find-route, starting waypoint")
+	flag.StringVar(&toPlace, "to", "",
+		"with -m, measure to waypoint, latitude,longitude pair, or distance; " +
+			"with -find-route, ending waypoint")
+	flag.StringVar(&travelName, "travel", "", "name of route for which to estimate travel time")
+	flag.StringVar(&travelRate, "rate", "",
+		"with -travel, name of travelRate to use instead of the route's own travel rate")
+	flag.StringVar(&matrixPlaces, "matrix", "",
+		"comma-separated names of places between which to measure distances along all paths")
+	flag.StringVar(&matrixFormat, "matrix-format", "csv",
+		"write -matrix output as 'csv' or 'json'")
+	flag.StringVar(&findRouteName, "find-route", "",
+		"with -from and -to, name of route to synthesize along the shortest connection")
+	flag.StringVar(&findWithin, "within", "",
+		"with -find-route, comma-separated names of items whose paths the route may use")
+	flag.StringVar(&alternatives, "alt", "",
+		"with -m, comma-separated names of route alternatives to take instead of the defaults")
 	flag.BoolVar(&asMiles, "miles", false, "measure distances in miles, not meters")
 	flag.BoolVar(&checkRoutes, "check-routes", false, "verify expected route lengths")
 	flag.BoolVar(&relaxRouteCheck, "relax-route-check", false,
 		"relax route-continuity check (debugging aid)")
+	flag.Float64Var(&snapTolerance, "snap", 0.0,
+		"report path vertices and waypoints within this many meters of one another")
+	flag.BoolVar(&snapMerge, "snap-merge", false,
+		"with -snap, move nearly coincident points to a single point")
+	flag.StringVar(&explainName, "explain-threading", "",
+		"name of route or segment whose threading to explain")
+	flag.BoolVar(&fixThreading, "fix", false,
+		"write waypoints suggested for ambiguous threading into the source files")
+	flag.StringVar(&crossingCheck, "crossings", "",
+		"find paths crossing between vertices: 'report' them or 'insert' shared vertices")
+	flag.StringVar(&atYear, "at-year", "",
+		"write only the items active in the given year to -g, -geojson, and -kml output")
+	flag.StringVar(&betweenYears, "between", "",
+		"write only the items active at some time in the given years, e.g. 1633,1704")
 	flag.Parse()
 
+	var outputOptions vectordata.OutputOptions
+	if len(generateFile) > 0 {
+		if len(generateFormat) == 0 {
+			switch strings.ToLower(filepath.Ext(generateFile)) {
+			case ".json":
+				generateFormat = "json"
+			case ".mjs":
+				generateFormat = "module"
+			default:
+				generateFormat = "global"
+			}
+		}
+		if splitLayers && generateFile == "-" {
+			fatal("the -split switch requires a file name for the -g switch")
+		}
+		switch generateFormat {
+		case "global":
+			outputOptions = vectordata.OutputOptions{Wrapper: vectordata.GlobalOutput,
+				GlobalName: globalName}
+		case "json":
+			outputOptions = vectordata.OutputOptions{Wrapper: vectordata.JsonOutput}
+		case "module":
+			outputOptions = vectordata.OutputOptions{Wrapper: vectordata.ModuleOutput}
+		default:
+			fatal("argument to the -g-format switch must be global, json, or module")
+		}
+		if len(simplify) > 0 {
+			for _, field := range strings.Split(simplify, ",") {
+				tolerance, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+				if err != nil || tolerance <= 0 {
+					fatal("tolerance '%s' in -simplify switch is not a positive number",
+						field)
+				}
+				outputOptions.Tolerances = append(outputOptions.Tolerances, tolerance)
+			}
+		}
+		switch pointsEncoding {
+		case "fixed":
+			outputOptions.PointsEncoding = vectordata.FixedPointEncoding
+		case "delta":
+			outputOptions.PointsEncoding = vectordata.DeltaEncoding
+		case "polyline6":
+			outputOptions.PointsEncoding = vectordata.Polyline6Encoding
+		default:
+			fatal("argument to the -points switch must be fixed, delta, or polyline6")
+		}
+	}
+
+	if len(gpxFile) > 0 {
+		if len(gpxItems) == 0 {
+			fatal("the -gpx switch requires the -gpx-items switch")
+		}
+		if gpxAs != "trk" && gpxAs != "rte" {
+			fatal("argument to the -gpx-as switch must be 'trk' or 'rte'")
+		}
+	}
+
+	if len(matrixPlaces) > 0 && matrixFormat != "csv" && matrixFormat != "json" {
+		fatal("argument to the -matrix-format switch must be 'csv' or 'json'")
+	}
+
+	if len(findRouteName) > 0 && (len(fromPlace) == 0 || len(toPlace) == 0) {
+		fatal("the -find-route switch requires the -from and -to switches")
+	}
+
+	if snapTolerance < 0 || (snapMerge && snapTolerance == 0) {
+		fatal("the -snap switch requires a tolerance greater than zero")
+	}
+
+	var activeFrom, activeTo int
+	if len(atYear) > 0 && len(betweenYears) > 0 {
+		fatal("the -at-year and -between switches may not be used together")
+	}
+	if len(atYear) > 0 {
+		year, err := strconv.Atoi(strings.TrimSpace(atYear))
+		if err != nil {
+			fatal("argument to the -at-year switch must be a year")
+		}
+		activeFrom, activeTo = year, year
+	}
+	if len(betweenYears) > 0 {
+		first, last, found := strings.Cut(betweenYears, ",")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err == nil {
+			activeTo, err = strconv.Atoi(strings.TrimSpace(last))
+		}
+		if !found || err != nil || activeTo < from {
+			fatal("argument to the -between switch must be two years in order, e.g. 1633,1704")
+		}
+		activeFrom = from
+	}
+
 	if !isDir(sourceDir) {
 		fatal("source directory %s does not exist", sourceDir)
 	}
@@ -58,43 +214,151 @@ func main() {
 			fatal(err.Error())
 		}
 	}
+	// GeoJSON and GPX files are read last so that the config section is in place
+	for _, format := range []struct {
+		pattern string
+		consume func(string, io.Reader) error
+	}{
+		{"/*.geojson", vdReader.ConsumeGeoJson},
+		{"/*.gpx", vdReader.ConsumeGpx},
+	} {
+		names, err = filepath.Glob(sourceDir + format.pattern)
+		if err != nil {
+			fatal(err.Error())
+		}
+		for _, filename := range names {
+			fh, err := os.Open(filename)
+			if err != nil {
+				fatal(err.Error())
+			}
+			err = format.consume(filename, fh)
+			fh.Close()
+			if err != nil {
+				fatal(err.Error())
+			}
+		}
+	}
+	if len(earthModel) > 0 {
+		fields := strings.Split(earthModel, ",")
+		var parameters []float64
+		for _, field := range fields[1:] {
+			parameter, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				fatal("parameter '%s' in -earth-model switch is not a number", field)
+			}
+			parameters = append(parameters, parameter)
+		}
+		err = vd.SetEarthModel(fields[0], parameters)
+		if err != nil {
+			fatal(err.Error())
+		}
+	}
+	if len(crossingCheck) > 0 {
+		err = vd.SetCrossingCheck(crossingCheck)
+		if err != nil {
+			fatal(err.Error())
+		}
+	}
+	if snapTolerance > 0 {
+		err = vd.SetSnapTolerance(snapTolerance, snapMerge)
+		if err != nil {
+			fatal(err.Error())
+		}
+	}
 	err = vd.ResolveReferences()
 	if err != nil {
 		fatal(err.Error())
 	}
+	if len(explainName) > 0 {
+		vd.SetExplainThreading(explainName)
+	}
 	err = vd.CheckAndReformRoutes()
 	if err != nil {
 		fatal(err.Error())
 	}
+	if len(explainName) > 0 {
+		explanation, err := vd.ThreadingExplanation()
+		if err != nil {
+			fatal(err.Error())
+		}
+		fmt.Print(explanation)
+	}
+	for _, crossing := range vd.GeometricCrossings() {
+		fmt.Fprintln(os.Stderr, crossing.String())
+	}
+	for _, near := range vd.NearCoincidences() {
+		fmt.Fprintln(os.Stderr, near.String())
+	}
 	errs := vd.DeferredErrors()
 	if len(errs) > 0 {
 		for _, err := range errs {
 			fmt.Fprintln(os.Stderr, err.Error())
 		}
+		for _, suggestion := range vd.ThreadingSuggestions() {
+			fmt.Fprintln(os.Stderr, suggestion.String())
+		}
+		if fixThreading {
+			fixSourceFiles(vd)
+		}
 		if !relaxRouteCheck {
 			fatal("Exiting with %d error(s)", len(errs))
 		}
 	}
 
-	if len(generateFile) > 0 {
-		blob, err := vd.GenerateJs()
+	if len(atYear) > 0 || len(betweenYears) > 0 {
+		err = vd.KeepActiveBetween(activeFrom, activeTo)
 		if err != nil {
 			fatal(err.Error())
 		}
-		var outfile *os.File
-		if generateFile == "-" {
-			outfile = os.Stdout
+	}
+
+	if len(generateFile) > 0 {
+		if splitLayers {
+			files, err := vd.GenerateLayerFiles(generateFile, outputOptions)
+			if err != nil {
+				fatal(err.Error())
+			}
+			for _, file := range files {
+				writeOutputFile(file.FileName, file.Blob)
+			}
 		} else {
-			outfile, err = os.Create(generateFile)
+			blob, err := vd.GenerateOutput(outputOptions)
 			if err != nil {
 				fatal(err.Error())
 			}
+			writeOutputFile(generateFile, blob)
+		}
+	}
+
+	if len(geojsonFile) > 0 {
+		blob, err := vd.GenerateGeoJson()
+		if err != nil {
+			fatal(err.Error())
+		}
+		writeOutputFile(geojsonFile, blob)
+	}
+
+	if len(kmlFile) > 0 {
+		var blob string
+		if strings.HasSuffix(strings.ToLower(kmlFile), ".kmz") {
+			var kmz []byte
+			kmz, err = vd.GenerateKmz()
+			blob = string(kmz)
+		} else {
+			blob, err = vd.GenerateKml()
 		}
-		_, err = outfile.Write([]byte(blob))
 		if err != nil {
 			fatal(err.Error())
 		}
-		outfile.Close()
+		writeOutputFile(kmlFile, blob)
+	}
+
+	if len(gpxFile) > 0 {
+		blob, err := vd.GenerateGpx(splitNames(gpxItems), gpxAs == "rte")
+		if err != nil {
+			fatal(err.Error())
+		}
+		writeOutputFile(gpxFile, blob)
 	}
 
 	if checkRoutes {
@@ -119,11 +383,131 @@ func main() {
 		}
 	}
 
+	if len(areaName) > 0 {
+		squareMetersPerUnit, err := vd.SquareMetersPerAreaUnit(areaUnit)
+		if err != nil {
+			fatal(err.Error())
+		}
+		measurement, err := vd.MeasureArea(areaName)
+		if err != nil {
+			fatal(err.Error())
+		}
+		unitName := areaUnit
+		if areaUnit != "acre" && areaUnit != "acres" {
+			unitName = "square " + areaUnit
+		}
+		fmt.Printf("area %.1f %s (%.2f acres); perimeter %.1f meters (%.2f miles)\n",
+			measurement.SquareMeters / squareMetersPerUnit, unitName,
+			measurement.SquareMeters / great.SQUARE_METERS_PER_ACRE,
+			measurement.PerimeterMeters, measurement.PerimeterMeters / great.METERS_PER_MILE)
+		if measurement.NumItems > 1 {
+			fmt.Printf("total of %d items\n", measurement.NumItems)
+		}
+	}
+
+	if len(travelRate) > 0 && len(travelName) == 0 {
+		fatal("the -rate switch requires the -travel switch")
+	}
+	if len(travelName) > 0 {
+		estimate, err := vd.EstimateTravel(travelName, travelRate)
+		if err != nil {
+			fatal(err.Error())
+		}
+		for _, leg := range estimate.Legs {
+			fmt.Printf(" %s: %.2f %s (%.1f meters, %.2f miles)\n", leg.Name, leg.Time,
+				estimate.Period, leg.Meters, leg.Meters / great.METERS_PER_MILE)
+		}
+		fmt.Printf("%s: %.2f %s (%.1f meters, %.2f miles)\n", estimate.Route, estimate.Time,
+			estimate.Period, estimate.Meters, estimate.Meters / great.METERS_PER_MILE)
+	}
+
+	if len(matrixPlaces) > 0 {
+		matrix, err := vd.MeasureDistanceMatrix(splitNames(matrixPlaces))
+		if err != nil {
+			fatal(err.Error())
+		}
+		metersPerUnit, unitName := 1.0, "meters"
+		if asMiles {
+			metersPerUnit, unitName = great.METERS_PER_MILE, "miles"
+		}
+		var blob string
+		if matrixFormat == "json" {
+			blob, err = matrix.JSON(metersPerUnit, unitName)
+		} else {
+			blob, err = matrix.CSV(metersPerUnit)
+		}
+		if err != nil {
+			fatal(err.Error())
+		}
+		fmt.Print(blob)
+	}
+
+	if len(findRouteName) > 0 {
+		var within []string
+		if len(findWithin) > 0 {
+			within = splitNames(findWithin)
+		}
+		text, _, err := vd.FindRoute(findRouteName, fromPlace, toPlace, within)
+		if err != nil {
+			fatal(err.Error())
+		}
+		fmt.Print(text)
+	}
+
 	if len(measureName) > 0 {
 		if upToDistance < 0 {
 			fatal("argument to the -u switch must not be negative")
 		}
-		if upToDistance == 0 {
+		if len(alternatives) > 0 && (len(fromPlace) > 0 || upToDistance > 0) {
+			fatal("the -alt switch may not be used with -from, -to, or -u")
+		}
+		if len(toPlace) > 0 && len(fromPlace) == 0 {
+			fatal("the -to switch requires the -from switch")
+		}
+		if len(fromPlace) > 0 && len(toPlace) == 0 && upToDistance == 0 {
+			fatal("the -from switch requires the -to or -u switch")
+		}
+		metersPerUnit := 1.0
+		if asMiles {
+			metersPerUnit = great.METERS_PER_MILE
+		}
+		var from vectordata.RoutePlace
+		if len(fromPlace) > 0 {
+			var err error
+			from, err = vectordata.ParseRoutePlace(fromPlace, metersPerUnit)
+			if err != nil {
+				fatal("-from: %s", err)
+			}
+		}
+		if len(toPlace) > 0 {
+			to, err := vectordata.ParseRoutePlace(toPlace, metersPerUnit)
+			if err != nil {
+				fatal("-to: %s", err)
+			}
+			interval, err := vd.MeasureBetween(measureName, from, to)
+			if err != nil {
+				fatal(err.Error())
+			}
+			fmt.Printf("%0.1f meters (%0.2f miles) along %s from %s to %s\n",
+				interval.Meters, interval.Meters / great.METERS_PER_MILE, measureName,
+				from, to)
+			for _, off := range []struct{place vectordata.RoutePlace; meters float64}{
+				{from, interval.FromOffRoute}, {to, interval.ToOffRoute},
+			} {
+				if off.meters >= 0.05 {
+					fmt.Printf(" %s is %.1f meters from %s\n", off.place, off.meters,
+						measureName)
+				}
+			}
+		} else if len(alternatives) > 0 {
+			names := splitNames(alternatives)
+			distance, err := vd.MeasureRouteWithAlternatives(measureName, names)
+			if err != nil {
+				fatal(err.Error())
+			}
+			fmt.Printf("%0.1f meters (%0.2f miles) by way of %s\n", distance,
+				distance / great.METERS_PER_MILE, strings.Join(names, ", "))
+		} else if upToDistance == 0 {
 			distance, err := vd.MeasurePath(measureName)
 			if err != nil {
 				fatal(err.Error())
@@ -131,17 +515,22 @@ func main() {
 			fmt.Printf("%0.1f meters (%0.2f miles)\n", distance,
 				distance / great.METERS_PER_MILE)
 		} else {
-			if asMiles {
-				upToDistance *= great.METERS_PER_MILE
+			upToDistance *= metersPerUnit
+			var start *vectordata.RoutePlace
+			if len(fromPlace) > 0 {
+				start = &from
 			}
-			lat, long, distance, pathName, index, err := vd.MeasurePathUpTo(
-				measureName, upToDistance)
+			pos, err := vd.MeasurePathToPositionFrom(measureName, start, upToDistance)
 			if err != nil {
 				fatal(err.Error())
 			}
+			if len(fromPlace) > 0 {
+				fmt.Printf("Starting from %s:\n", from)
+			}
 			fmt.Printf("Distance to latitude %.6f, longitude %.6f: %.1f meters " +
-				"(%.1f miles)\n at point %d along path %s\n", lat, long, distance,
-				distance / great.METERS_PER_MILE, index, pathName)
+				"(%.1f miles)\n bearing %.1f° between points %d and %d along path %s\n",
+				pos.Lat, pos.Long, pos.Distance, pos.Distance / great.METERS_PER_MILE,
+				pos.Bearing, pos.FromIndex, pos.ToIndex, pos.PathName)
 		}
 	}
 }
@@ -157,6 +546,54 @@ func isDir(filename string) bool {
 }
 
 
+// Splits a comma-separated list of names, allowing spaces around the commas
+func splitNames(list string) []string {
+	names := strings.Split(list, ",")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+	}
+	return names
+}
+
+
+func fixSourceFiles(vd *vectordata.VectorData) {
+	files, unfixed, err := vd.FixThreading(func (filename string) (string, error) {
+		blob, err := os.ReadFile(filename)
+		return string(blob), err
+	})
+	if err != nil {
+		fatal(err.Error())
+	}
+	for _, file := range files {
+		writeOutputFile(file.FileName, file.Blob)
+		fmt.Fprintf(os.Stderr, "wrote suggested waypoints into %s\n", file.FileName)
+	}
+	for _, suggestion := range unfixed {
+		fmt.Fprintf(os.Stderr, "could not write suggestion for %s into its source\n",
+			suggestion.Source)
+	}
+}
+
+
+func writeOutputFile(filename, blob string) {
+	var outfile *os.File
+	var err error
+	if filename == "-" {
+		outfile = os.Stdout
+	} else {
+		outfile, err = os.Create(filename)
+		if err != nil {
+			fatal(err.Error())
+		}
+	}
+	_, err = outfile.Write([]byte(blob))
+	if err != nil {
+		fatal(err.Error())
+	}
+	outfile.Close()
+}
+
+
 func fatal(msg string, args ...any) {
 	fmt.Fprintf(os.Stderr, msg + "\n", args...)
 	os.Exit(1)
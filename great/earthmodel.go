@@ -0,0 +1,343 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package great
+
+//Distance computations on a choice of spherical or ellipsoidal models of the earth
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+/**
+ * Shape of the earth used for measuring distances.  A flattening of zero makes the model a
+ * sphere whose radius is the semi-major axis.
+ */
+type EarthModel struct {
+	Name string
+	semiMajor, flattening float64
+}
+
+var (
+	// Sphere matching the package-level functions
+	NorthFloridaSphere = EarthModel{Name: "sphere", semiMajor: EARTH_RADIUS}
+	WGS84 = EarthModel{Name: "wgs84", semiMajor: 6378137, flattening: 1 / 298.257223563}
+)
+
+const (
+	// Sanity limits on model parameters, to catch values given in the wrong units
+	minEarthRadius = 6300000
+	maxEarthRadius = 6400000
+	minInverseFlattening = 100
+
+	vincentyIterationLimit = 200
+	vincentyTolerance = 1e-12
+)
+
+
+/**
+ * Returns a model given by name and parameters:  a sphere with an optional radius in meters,
+ * the WGS 84 ellipsoid, or an ellipsoid of given semi-major axis in meters and inverse
+ * flattening
+ */
+func NewEarthModel(name string, params []float64) (EarthModel, error) {
+	switch name {
+	case "sphere":
+		switch len(params) {
+		case 0:
+			return NorthFloridaSphere, nil
+		case 1:
+			return NewSphere(params[0])
+		}
+		return EarthModel{}, fmt.Errorf("a sphere takes at most one parameter, the radius")
+	case "wgs84":
+		if len(params) > 0 {
+			return EarthModel{}, fmt.Errorf("the wgs84 model takes no parameters")
+		}
+		return WGS84, nil
+	case "ellipsoid":
+		if len(params) != 2 {
+			return EarthModel{}, fmt.Errorf("an ellipsoid takes two parameters, " +
+				"the semi-major axis and the inverse flattening")
+		}
+		return NewEllipsoid(params[0], params[1])
+	}
+	return EarthModel{}, fmt.Errorf("unknown earth model %s; expected sphere, wgs84, or ellipsoid",
+		name)
+}
+
+
+/**
+ * Returns a spherical model of the given radius in meters
+ */
+func NewSphere(radius float64) (EarthModel, error) {
+	if radius < minEarthRadius || radius > maxEarthRadius {
+		return EarthModel{}, fmt.Errorf("sphere radius %s is not between %d and %d meters",
+			formatParameter(radius), minEarthRadius, maxEarthRadius)
+	}
+	return EarthModel{Name: "sphere", semiMajor: radius}, nil
+}
+
+
+/**
+ * Returns an ellipsoidal model of the given semi-major axis in meters and inverse flattening
+ */
+func NewEllipsoid(semiMajor, inverseFlattening float64) (EarthModel, error) {
+	if semiMajor < minEarthRadius || semiMajor > maxEarthRadius {
+		return EarthModel{}, fmt.Errorf("semi-major axis %s is not between %d and %d meters",
+			formatParameter(semiMajor), minEarthRadius, maxEarthRadius)
+	}
+	if inverseFlattening < minInverseFlattening {
+		return EarthModel{}, fmt.Errorf("inverse flattening %s is less than %d",
+			formatParameter(inverseFlattening), minInverseFlattening)
+	}
+	return EarthModel{Name: "ellipsoid", semiMajor: semiMajor,
+		flattening: 1 / inverseFlattening}, nil
+}
+
+
+/**
+ * Describes the model along with its parameters
+ */
+func (em EarthModel) String() string {
+	switch {
+	case em == NorthFloridaSphere || em == WGS84:
+		return em.Name
+	case em.flattening == 0:
+		return em.Name + " " + formatParameter(em.semiMajor)
+	}
+	return em.Name + " " + formatParameter(em.semiMajor) + " " +
+		formatParameter(1 / em.flattening)
+}
+
+// Writes a parameter in plain decimal notation, as given in the configuration
+func formatParameter(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+
+/**
+ * Computes the distance in meters between two points given by their latitude and longitude
+ * in radians.  Uses the Haversine Formula for spheres and Vincenty's inverse formula for
+ * ellipsoids.
+ */
+func (em EarthModel) MetersBetweenPoints(p1Lat, p1Long, p2Lat, p2Long float64) float64 {
+	if em.flattening == 0 {
+		return haversineMeters(em.semiMajor, p1Lat, p1Long, p2Lat, p2Long)
+	}
+	meters, converged := em.vincentyInverse(p1Lat, p1Long, p2Lat, p2Long)
+	if !converged {
+		// Vincenty's method fails for nearly antipodal points, which are of no
+		// practical concern here; fall back to a sphere of the mean radius.
+		a := em.semiMajor
+		b := a * (1 - em.flattening)
+		return haversineMeters((2 * a + b) / 3, p1Lat, p1Long, p2Lat, p2Long)
+	}
+	return meters
+}
+
+
+/** Computes length in meters of path of latitude/longitude pairs in degrees
+ */
+func (em EarthModel) MetersInPath(path []float64) float64 {
+	if len(path) < 4 {
+		return 0
+	}
+	var accum float64
+	prevLat, prevLong := path[0] * DEG_TO_RADIANS, path[1] * DEG_TO_RADIANS
+	for i := 2; i < len(path) - 1; i += 2 {
+		lat, long := path[i] * DEG_TO_RADIANS, path[i+1] * DEG_TO_RADIANS
+		accum += em.MetersBetweenPoints(prevLat, prevLong, lat, long)
+		prevLat, prevLong = lat, long
+	}
+	return accum
+}
+
+
+/** Computes length in meters between pairs of points; returns a slice of distances
+ */
+func (em EarthModel) MetersBetweenPointPairs(pairs []float64) []float64 {
+	if len(pairs) < 4 {
+		return []float64{}
+	}
+	steps := make([]float64, (len(pairs) >> 1) - 1)
+	prevLat, prevLong := pairs[0] * DEG_TO_RADIANS, pairs[1] * DEG_TO_RADIANS
+	o := 0
+	for i := 2; i < len(pairs) - 1; i += 2 {
+		lat, long := pairs[i] * DEG_TO_RADIANS, pairs[i+1] * DEG_TO_RADIANS
+		steps[o] = em.MetersBetweenPoints(prevLat, prevLong, lat, long)
+		o++
+		prevLat, prevLong = lat, long
+	}
+	return steps
+}
+
+
+/**
+ * Finds the nearest point to a given point on the segment between two other points, returning
+ * the distance in meters to it on the model and the fraction of the way along the segment at
+ * which it lies.  The point is found on the sphere, whose great circles differ negligibly from
+ * the model's geodesics over the length of a path segment.  All points are given in radians.
+ */
+func (em EarthModel) NearestOnSegment(pLat, pLong, s1Lat, s1Long, s2Lat, s2Long float64) (float64,
+		float64) {
+	_, fraction := NearestOnSegment(pLat, pLong, s1Lat, s1Long, s2Lat, s2Long)
+	switch fraction {
+	case 0:
+		return em.MetersBetweenPoints(pLat, pLong, s1Lat, s1Long), 0
+	case 1:
+		return em.MetersBetweenPoints(pLat, pLong, s2Lat, s2Long), 1
+	}
+	lat, long := IntermediatePoint(s1Lat, s1Long, s2Lat, s2Long, fraction)
+	return em.MetersBetweenPoints(pLat, pLong, lat, long), fraction
+}
+
+
+/**
+ * Computes the distance in meters on the model from a point to the nearest point on the
+ * segment between two other points.  All points are given in radians.
+ */
+func (em EarthModel) MetersFromSegment(pLat, pLong, s1Lat, s1Long, s2Lat, s2Long float64) float64 {
+	meters, _ := em.NearestOnSegment(pLat, pLong, s1Lat, s1Long, s2Lat, s2Long)
+	return meters
+}
+
+
+/**
+ * Radius of the sphere having the same surface area as the model.  Areas are computed on
+ * this sphere.
+ */
+func (em EarthModel) AuthalicRadius() float64 {
+	if em.flattening == 0 {
+		return em.semiMajor
+	}
+	a, f := em.semiMajor, em.flattening
+	e := math.Sqrt(f * (2 - f))
+	b := a * (1 - f)
+	return math.Sqrt((a * a + b * b / e * math.Atanh(e)) / 2)
+}
+
+
+/**
+ * Computes the area in square meters of the polygon formed by a ring of latitude/longitude pairs
+ * in degrees.  The ring is taken to be closed whether or not the last point repeats the first.
+ * Uses the spherical-excess formula for polygons with great-circle edges on the authalic
+ * sphere.
+ */
+func (em EarthModel) SquareMetersInPolygon(ring []float64) float64 {
+	n := len(ring) >> 1
+	if n < 3 {
+		return 0
+	}
+	var accum float64
+	for i := 0; i < n; i++ {
+		j := (i + 1) % n
+		lat1, long1 := ring[i*2] * DEG_TO_RADIANS, ring[i*2 + 1] * DEG_TO_RADIANS
+		lat2, long2 := ring[j*2] * DEG_TO_RADIANS, ring[j*2 + 1] * DEG_TO_RADIANS
+		accum += (long2 - long1) * (2 + math.Sin(lat1) + math.Sin(lat2))
+	}
+	radius := em.AuthalicRadius()
+	return math.Abs(accum * radius * radius / 2)
+}
+
+
+/**
+ * Computes the perimeter in meters of the polygon formed by a ring of latitude/longitude pairs
+ * in degrees, including the closing edge.
+ */
+func (em EarthModel) MetersAroundPolygon(ring []float64) float64 {
+	n := len(ring)
+	if n < 4 {
+		return 0
+	}
+	closed := append(append([]float64{}, ring...), ring[0], ring[1])
+	if ring[0] == ring[n-2] && ring[1] == ring[n-1] {
+		closed = ring
+	}
+	return em.MetersInPath(closed)
+}
+
+
+/**
+ * Computes the area in square meters of the spherical cap within the given radius in meters
+ */
+func (em EarthModel) SquareMetersInCircle(radius float64) float64 {
+	earthRadius := em.AuthalicRadius()
+	return 2 * math.Pi * earthRadius * earthRadius * (1 - math.Cos(radius / earthRadius))
+}
+
+
+/**
+ * Computes the circumference in meters of the circle of the given radius in meters
+ */
+func (em EarthModel) MetersAroundCircle(radius float64) float64 {
+	earthRadius := em.AuthalicRadius()
+	return 2 * math.Pi * earthRadius * math.Sin(radius / earthRadius)
+}
+
+
+func haversineMeters(radius, p1Lat, p1Long, p2Lat, p2Long float64) float64 {
+	sinDLat := math.Sin((p1Lat - p2Lat) / 2)
+	sinDLong := math.Sin((p1Long - p2Long) / 2)
+	a := sinDLat * sinDLat + math.Cos(p1Lat) * math.Cos(p2Lat) * sinDLong * sinDLong
+	return radius * 2 * math.Asin(math.Sqrt(a))
+}
+
+
+/**
+ * Vincenty's inverse formula for the geodesic distance between two points on an ellipsoid
+ * of revolution.  See T. Vincenty, "Direct and Inverse Solutions of Geodesics on the
+ * Ellipsoid with Application of Nested Equations", Survey Review XXIII (176), 1975.
+ */
+func (em EarthModel) vincentyInverse(p1Lat, p1Long, p2Lat, p2Long float64) (float64, bool) {
+	a, f := em.semiMajor, em.flattening
+	b := a * (1 - f)
+	L := p2Long - p1Long
+	U1 := math.Atan((1 - f) * math.Tan(p1Lat))
+	U2 := math.Atan((1 - f) * math.Tan(p2Lat))
+	sinU1, cosU1 := math.Sincos(U1)
+	sinU2, cosU2 := math.Sincos(U2)
+
+	lambda := L
+	var sinSigma, cosSigma, sigma, cosSqAlpha, cos2SigmaM float64
+	for iteration := 0; ; iteration++ {
+		if iteration >= vincentyIterationLimit {
+			return 0, false
+		}
+		sinLambda, cosLambda := math.Sincos(lambda)
+		t1 := cosU2 * sinLambda
+		t2 := cosU1 * sinU2 - sinU1 * cosU2 * cosLambda
+		sinSigma = math.Sqrt(t1 * t1 + t2 * t2)
+		if sinSigma == 0 {
+			// Coincident points
+			return 0, true
+		}
+		cosSigma = sinU1 * sinU2 + cosU1 * cosU2 * cosLambda
+		sigma = math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha = 1 - sinAlpha * sinAlpha
+		if cosSqAlpha != 0 {
+			cos2SigmaM = cosSigma - 2 * sinU1 * sinU2 / cosSqAlpha
+		} else {
+			// Both points on the equator
+			cos2SigmaM = 0
+		}
+		C := f / 16 * cosSqAlpha * (4 + f * (4 - 3 * cosSqAlpha))
+		prevLambda := lambda
+		lambda = L + (1 - C) * f * sinAlpha * (sigma + C * sinSigma *
+			(cos2SigmaM + C * cosSigma * (-1 + 2 * cos2SigmaM * cos2SigmaM)))
+		if math.Abs(lambda - prevLambda) < vincentyTolerance {
+			break
+		}
+	}
+
+	uSq := cosSqAlpha * (a * a - b * b) / (b * b)
+	A := 1 + uSq / 16384 * (4096 + uSq * (-768 + uSq * (320 - 175 * uSq)))
+	B := uSq / 1024 * (256 + uSq * (-128 + uSq * (74 - 47 * uSq)))
+	deltaSigma := B * sinSigma * (cos2SigmaM + B / 4 * (cosSigma *
+		(-1 + 2 * cos2SigmaM * cos2SigmaM) - B / 6 * cos2SigmaM *
+		(-3 + 4 * sinSigma * sinSigma) * (-3 + 4 * cos2SigmaM * cos2SigmaM)))
+	return b * A * (sigma - deltaSigma), true
+}
@@ -12,6 +12,7 @@ import (
 const (
 	DEG_TO_RADIANS = math.Pi / 180
 	METERS_PER_MILE = 1609.344
+	SQUARE_METERS_PER_ACRE = 4046.8564224
 	EARTH_RADIUS = 6372768
 //Earth radius is computed according to the WGS 84 datum at 30.174861°N, the latitude midway
 // between St. Augustine (29.894722°N) and Tallahassee (30.455000°N)
@@ -23,45 +24,123 @@ const (
  * in radians.  Uses the Haversine Formula with an earth's radius at north Florida.
  */
 func MetersBetweenPoints(p1Lat, p1Long, p2Lat, p2Long float64) float64 {
-	sinDLat := math.Sin((p1Lat - p2Lat) / 2)
-	sinDLong := math.Sin((p1Long - p2Long) / 2)
-	a := sinDLat * sinDLat + math.Cos(p1Lat) * math.Cos(p2Lat) * sinDLong * sinDLong
-	return EARTH_RADIUS * 2 * math.Asin(math.Sqrt(a))
+	return haversineMeters(EARTH_RADIUS, p1Lat, p1Long, p2Lat, p2Long)
 }
 
 
 /** Computes length in meters of path of latitude/longitude pairs in degrees
  */
 func MetersInPath(path []float64) float64 {
-	if len(path) < 4 {
-		return 0
-	}
-	var accum float64
-	prevLat, prevLong := path[0] * DEG_TO_RADIANS, path[1] * DEG_TO_RADIANS
-	for i := 2; i < len(path) - 1; i += 2 {
-		lat, long := path[i] * DEG_TO_RADIANS, path[i+1] * DEG_TO_RADIANS
-		accum += MetersBetweenPoints(prevLat, prevLong, lat, long)
-		prevLat, prevLong = lat, long
-	}
-	return accum
+	return NorthFloridaSphere.MetersInPath(path)
 }
 
 
 /** Computes length in meters between pairs of points; returns a slice of distances
  */
 func MetersBetweenPointPairs(pairs []float64) []float64 {
-	if len(pairs) < 4 {
-		return []float64{}
+	return NorthFloridaSphere.MetersBetweenPointPairs(pairs)
+}
+
+
+
+/**
+ * Computes the initial bearing in radians, clockwise from north, of the great-circle path from
+ * the first point to the second.  Points are given in radians.
+ */
+func InitialBearing(p1Lat, p1Long, p2Lat, p2Long float64) float64 {
+	dLong := p2Long - p1Long
+	y := math.Sin(dLong) * math.Cos(p2Lat)
+	x := math.Cos(p1Lat) * math.Sin(p2Lat) -
+		math.Sin(p1Lat) * math.Cos(p2Lat) * math.Cos(dLong)
+	return math.Atan2(y, x)
+}
+
+
+/**
+ * Computes the point the given fraction of the way along the great-circle path from the first
+ * point to the second.  Points are given in radians.
+ */
+func IntermediatePoint(p1Lat, p1Long, p2Lat, p2Long, fraction float64) (float64, float64) {
+	angle := MetersBetweenPoints(p1Lat, p1Long, p2Lat, p2Long) / EARTH_RADIUS
+	if angle == 0 {
+		return p1Lat, p1Long
+	}
+	a := math.Sin((1 - fraction) * angle) / math.Sin(angle)
+	b := math.Sin(fraction * angle) / math.Sin(angle)
+	x := a * math.Cos(p1Lat) * math.Cos(p1Long) + b * math.Cos(p2Lat) * math.Cos(p2Long)
+	y := a * math.Cos(p1Lat) * math.Sin(p1Long) + b * math.Cos(p2Lat) * math.Sin(p2Long)
+	z := a * math.Sin(p1Lat) + b * math.Sin(p2Lat)
+	return math.Atan2(z, math.Sqrt(x * x + y * y)), math.Atan2(y, x)
+}
+
+
+/**
+ * Computes the distance in meters from a point to the nearest point on the great-circle
+ * segment between two other points.  All points are given in radians.
+ */
+func MetersFromSegment(pLat, pLong, s1Lat, s1Long, s2Lat, s2Long float64) float64 {
+	meters, _ := NearestOnSegment(pLat, pLong, s1Lat, s1Long, s2Lat, s2Long)
+	return meters
+}
+
+
+/**
+ * Finds the nearest point to a given point on the great-circle segment between two other
+ * points.  Returns the distance in meters to the nearest point and the fraction of the way
+ * along the segment at which it lies.  All points are given in radians.
+ */
+func NearestOnSegment(pLat, pLong, s1Lat, s1Long, s2Lat, s2Long float64) (float64, float64) {
+	toStart := MetersBetweenPoints(s1Lat, s1Long, pLat, pLong)
+	segment := MetersBetweenPoints(s1Lat, s1Long, s2Lat, s2Long)
+	if segment == 0 {
+		return toStart, 0
+	}
+	dBearing := InitialBearing(s1Lat, s1Long, pLat, pLong) -
+		InitialBearing(s1Lat, s1Long, s2Lat, s2Long)
+	if math.Cos(dBearing) < 0 {
+		// Point is behind the start of the segment
+		return toStart, 0
 	}
-	steps := make([]float64, (len(pairs) >> 1) - 1)
-	prevLat, prevLong := pairs[0] * DEG_TO_RADIANS, pairs[1] * DEG_TO_RADIANS
-	o := 0
-	for i := 2; i < len(pairs) - 1; i += 2 {
-		lat, long := pairs[i] * DEG_TO_RADIANS, pairs[i+1] * DEG_TO_RADIANS
-		steps[o] = MetersBetweenPoints(prevLat, prevLong, lat, long)
-		o++
-		prevLat, prevLong = lat, long
+	crossTrack := math.Asin(math.Sin(toStart / EARTH_RADIUS) * math.Sin(dBearing))
+	alongTrack := math.Acos(math.Min(1, math.Cos(toStart / EARTH_RADIUS) /
+		math.Cos(crossTrack))) * EARTH_RADIUS
+	if alongTrack > segment {
+		return MetersBetweenPoints(s2Lat, s2Long, pLat, pLong), 1
 	}
-	return steps
+	return math.Abs(crossTrack) * EARTH_RADIUS, alongTrack / segment
+}
+
+
+/**
+ * Computes the area in square meters of the polygon formed by a ring of latitude/longitude pairs
+ * in degrees.  The ring is taken to be closed whether or not the last point repeats the first.
+ * Uses the spherical-excess formula for polygons with great-circle edges.
+ */
+func SquareMetersInPolygon(ring []float64) float64 {
+	return NorthFloridaSphere.SquareMetersInPolygon(ring)
+}
+
+
+/**
+ * Computes the perimeter in meters of the polygon formed by a ring of latitude/longitude pairs
+ * in degrees, including the closing edge.
+ */
+func MetersAroundPolygon(ring []float64) float64 {
+	return NorthFloridaSphere.MetersAroundPolygon(ring)
 }
 
+
+/**
+ * Computes the area in square meters of the spherical cap within the given radius in meters
+ */
+func SquareMetersInCircle(radius float64) float64 {
+	return NorthFloridaSphere.SquareMetersInCircle(radius)
+}
+
+
+/**
+ * Computes the circumference in meters of the circle of the given radius in meters
+ */
+func MetersAroundCircle(radius float64) float64 {
+	return NorthFloridaSphere.MetersAroundCircle(radius)
+}
@@ -5,6 +5,7 @@ package great
 
 import (
 	"fmt"
+	"math"
 	"testing"
 )
 
@@ -75,3 +76,169 @@ func Test_measurePath(T *testing.T) {
 }
 
 
+
+
+func Test_metersFromSegment(T *testing.T) {
+	for i, test := range []struct{pLat, pLong, lat1, long1, lat2, long2 float64; want float64}{
+		// abeam the segment: 0.001 degree of latitude
+		{30.001, -83.5, 30.0, -83.51, 30.0, -83.49, 110.9},
+		// beyond either end: distance to the nearer endpoint
+		{30.0, -83.52, 30.0, -83.51, 30.0, -83.49, 962.9},
+		{30.0, -83.48, 30.0, -83.51, 30.0, -83.49, 962.9},
+		// on the segment
+		{30.0, -83.5, 30.0, -83.51, 30.0, -83.49, 0},
+		// degenerate segment
+		{30.001, -83.5, 30.0, -83.5, 30.0, -83.5, 111.2},
+	} {
+		meters := MetersFromSegment(test.pLat * DEG_TO_RADIANS, test.pLong * DEG_TO_RADIANS,
+			test.lat1 * DEG_TO_RADIANS, test.long1 * DEG_TO_RADIANS,
+			test.lat2 * DEG_TO_RADIANS, test.long2 * DEG_TO_RADIANS)
+		diff := meters - test.want
+		if diff > 0.4 || diff < -0.4 {
+			T.Fatalf("test %d: expected %.1f meters, got %.1f", i, test.want, meters)
+		}
+	}
+}
+
+
+func Test_intermediatePoint(T *testing.T) {
+	for i, test := range []struct{lat1, long1, lat2, long2, fraction, wantLat, wantLong float64} {
+		{30.0, -83.5, 30.0, -83.5, 0.5, 30.0, -83.5},
+		{30.0, -83.5, 31.0, -83.5, 0.0, 30.0, -83.5},
+		{30.0, -83.5, 31.0, -83.5, 1.0, 31.0, -83.5},
+		{30.0, -83.5, 31.0, -83.5, 0.25, 30.25, -83.5},
+		{0.0, 10.0, 0.0, 20.0, 0.3, 0.0, 13.0},
+		// Great circle between points of equal latitude bows toward the pole
+		{30.0, -84.0, 30.0, -82.0, 0.5, 30.003779, -83.0},
+	} {
+		lat, long := IntermediatePoint(test.lat1 * DEG_TO_RADIANS, test.long1 * DEG_TO_RADIANS,
+			test.lat2 * DEG_TO_RADIANS, test.long2 * DEG_TO_RADIANS, test.fraction)
+		lat /= DEG_TO_RADIANS
+		long /= DEG_TO_RADIANS
+		if math.Abs(lat - test.wantLat) > 5e-7 || math.Abs(long - test.wantLong) > 5e-7 {
+			T.Fatalf("test %d: expected %.6f %.6f, got %.6f %.6f", i, test.wantLat,
+				test.wantLong, lat, long)
+		}
+	}
+}
+
+
+func Test_nearestOnSegment(T *testing.T) {
+	for i, test := range []struct{pLat, pLong, lat1, long1, lat2, long2, want, fraction float64} {
+		{30.0, -83.5, 30.0, -83.5, 30.001, -83.5, 0, 0},
+		{30.0005, -83.501, 30.0, -83.5, 30.001, -83.5, 96.4, 0.5},
+		{30.0015, -83.5, 30.0, -83.5, 30.001, -83.5, 55.6, 1},
+		{29.999, -83.5, 30.0, -83.5, 30.001, -83.5, 111.2, 0},
+	} {
+		meters, fraction := NearestOnSegment(test.pLat * DEG_TO_RADIANS,
+			test.pLong * DEG_TO_RADIANS, test.lat1 * DEG_TO_RADIANS,
+			test.long1 * DEG_TO_RADIANS, test.lat2 * DEG_TO_RADIANS,
+			test.long2 * DEG_TO_RADIANS)
+		if math.Abs(meters - test.want) > 0.1 || math.Abs(fraction - test.fraction) > 0.001 {
+			T.Fatalf("test %d: expected %.1f meters at %.3f, got %.1f at %.3f", i,
+				test.want, test.fraction, meters, fraction)
+		}
+	}
+}
+
+
+func Test_modelNearestOnSegment(T *testing.T) {
+	for i, test := range []struct{pLat, pLong, lat1, long1, lat2, long2, want, fraction float64} {
+		{30.001, -83.5, 30.0, -83.51, 30.0, -83.49, 110.8, 0.5},
+		{30.0005, -83.501, 30.0, -83.5, 30.001, -83.5, 96.5, 0.5},
+		{30.0015, -83.5, 30.0, -83.5, 30.001, -83.5, 55.4, 1},
+	} {
+		meters, fraction := WGS84.NearestOnSegment(test.pLat * DEG_TO_RADIANS,
+			test.pLong * DEG_TO_RADIANS, test.lat1 * DEG_TO_RADIANS,
+			test.long1 * DEG_TO_RADIANS, test.lat2 * DEG_TO_RADIANS,
+			test.long2 * DEG_TO_RADIANS)
+		if math.Abs(meters - test.want) > 0.1 || math.Abs(fraction - test.fraction) > 0.001 {
+			T.Fatalf("test %d: expected %.1f meters at %.3f, got %.1f at %.3f", i,
+				test.want, test.fraction, meters, fraction)
+		}
+	}
+}
+
+
+func Test_area(T *testing.T) {
+	// 0.01 degree on a side at 30°N; area is R² Δlong (sin lat2 - sin lat1)
+	square := []float64{30.0, -83.0, 30.0, -82.99, 30.01, -82.99, 30.01, -83.0}
+	for i, test := range []struct{got, want, slop float64}{
+		{SquareMetersInPolygon(square), 1071321.2, 0.1},
+		{MetersAroundPolygon(square), 2 * (1112.2 + 963.3), 1},
+		{MetersAroundPolygon(append(square, 30.0, -83.0)), 2 * (1112.2 + 963.3), 1},
+		{SquareMetersInCircle(100), 31415.9, 0.1},
+		{MetersAroundCircle(100), 628.3, 0.1},
+		{SQUARE_METERS_PER_ACRE * 640, 2589988.1, 0.1},
+	} {
+		diff := test.got - test.want
+		if diff > test.slop || diff < -test.slop {
+			T.Fatalf("test %d: expected %.1f, got %.1f", i, test.want, test.got)
+		}
+	}
+}
+
+
+func Test_ellipsoid(T *testing.T) {
+	dms := func(d, m, s float64) float64 {
+		if d < 0 {
+			return (d - m / 60 - s / 3600) * DEG_TO_RADIANS
+		}
+		return (d + m / 60 + s / 3600) * DEG_TO_RADIANS
+	}
+	for i, test := range []struct{lat1, long1, lat2, long2 float64; want, slop float64}{
+		// Flinders Peak to Buninyong, Vincenty's own test line
+		{dms(-37, 57, 3.72030), dms(144, 25, 29.52440), dms(-37, 39, 10.15610),
+			dms(143, 55, 35.38390), 54972.271, 0.001},
+		{29.98313 * DEG_TO_RADIANS, -81.31244 * DEG_TO_RADIANS,
+			30.43812 * DEG_TO_RADIANS, -84.28132 * DEG_TO_RADIANS, 290255.7, 0.1},
+		{0.5, 0.5, 0.5, 0.5, 0, 0},
+		{0, 0, 0, DEG_TO_RADIANS, 111319.5, 0.1},
+		// Nearly antipodal:  falls back to the mean sphere
+		{0, 0, 0.5 * DEG_TO_RADIANS, 179.7 * DEG_TO_RADIANS, 19950277.3, 0.1},
+	} {
+		meters := WGS84.MetersBetweenPoints(test.lat1, test.long1, test.lat2, test.long2)
+		diff := meters - test.want
+		if diff > test.slop || diff < -test.slop {
+			T.Fatalf("test %d: expected %.3f meters, got %.3f", i, test.want, meters)
+		}
+	}
+}
+
+
+func Test_earthModels(T *testing.T) {
+	for i, test := range []struct{name string; params []float64; want, err string} {
+		{"wgs84", nil, "wgs84", ""},
+		{"sphere", nil, "sphere", ""},
+		{"sphere", []float64{6371000}, "sphere 6371000", ""},
+		{"ellipsoid", []float64{6378206.4, 294.9786982}, "ellipsoid 6378206.4 294.9786982", ""},
+		{"sphere", []float64{6371}, "",
+			"sphere radius 6371 is not between 6300000 and 6400000 meters"},
+		{"sphere", []float64{6371000, 1}, "",
+			"a sphere takes at most one parameter, the radius"},
+		{"wgs84", []float64{6378137}, "", "the wgs84 model takes no parameters"},
+		{"ellipsoid", []float64{6378137}, "",
+			"an ellipsoid takes two parameters, the semi-major axis and the inverse flattening"},
+		{"ellipsoid", []float64{6378137, 0.0033}, "", "inverse flattening 0.0033 is less than 100"},
+		{"flat", nil, "", "unknown earth model flat; expected sphere, wgs84, or ellipsoid"},
+	} {
+		model, err := NewEarthModel(test.name, test.params)
+		if len(test.err) > 0 {
+			if err == nil || err.Error() != test.err {
+				T.Fatalf("test %d: expected error '%s', got '%v'", i, test.err, err)
+			}
+		} else if err != nil {
+			T.Fatalf("test %d: %s", i, err)
+		} else if model.String() != test.want {
+			T.Fatalf("test %d: expected model '%s', got '%s'", i, test.want, model)
+		}
+	}
+	if r := WGS84.AuthalicRadius(); math.Abs(r - 6371007.2) > 0.1 {
+		T.Fatalf("expected WGS 84 authalic radius 6371007.2, got %.1f", r)
+	}
+	sphere, _ := NewSphere(6371000)
+	meters := sphere.MetersBetweenPoints(0, 0, 0, DEG_TO_RADIANS)
+	if math.Abs(meters - 111194.9) > 0.1 {
+		T.Fatalf("expected 111194.9 meters on 6371-km sphere, got %.1f", meters)
+	}
+}
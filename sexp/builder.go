@@ -0,0 +1,36 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package sexp
+
+// Constructs S-expressions on behalf of readers of source formats other than S-expressions so
+// that data from those formats may pass through the same grammar checks as that from .sexp
+// files.  Line numbers refer to the lines in the original source file.
+
+type Builder struct {
+	source *sourceInfo
+}
+
+func NewBuilder(filename string) Builder {
+	return Builder{&sourceInfo{filename}}
+}
+
+func (b Builder) List(lineno uint32, head string, items ...LispValue) LispList {
+	return newLispList(b.source, lineno, head, items)
+}
+
+func (b Builder) Symbol(lineno uint32, value string) LispScalar {
+	return newLispSymbol(b.source, lineno, value)
+}
+
+func (b Builder) String(lineno uint32, value string) LispScalar {
+	return newLispString(b.source, lineno, value)
+}
+
+func (b Builder) Float(lineno uint32, value string) LispScalar {
+	return newLispFloat(b.source, lineno, value)
+}
+
+func (b Builder) Error(lineno uint32, msg string, args... any) SexpError {
+	return newSexpError(b.source, lineno, msg, args...)
+}
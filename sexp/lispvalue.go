@@ -101,6 +101,17 @@ func (b ValueSource) SourceDescription() string {
 	return formSourceDescription(b.source, b.lineno)
 }
 
+func (b ValueSource) Filename() string {
+	if b.source == nil {
+		return ""
+	}
+	return b.source.filename
+}
+
+func (b ValueSource) Lineno() uint32 {
+	return b.lineno
+}
+
 
 type LispList struct {
 	ValueSource
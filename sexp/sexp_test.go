@@ -394,3 +394,17 @@ func Test_symbols (T *testing.T) {
 	}
 }
 
+
+
+func Test_builder (T *testing.T) {
+	b := NewBuilder("testfile")
+	l := b.List(3, "testlist", b.Symbol(4, "abc"), b.String(4, "def"),
+		b.List(5, "inner", b.Float(6, "12.3")))
+	valueOK(T, tstList{3, "testlist", []tstValue{
+		tstScalar{4, TSymbol, "abc"}, tstScalar{4, TString, "def"},
+		tstList{5, "inner", []tstValue{tstScalar{6, TFloat, "12.3"}}}}}, l)
+	err := b.Error(7, "bad %s", "thing")
+	if err.Error() != "testfile:7: bad thing" {
+		T.Fatalf("unexpected error message '%s'", err)
+	}
+}
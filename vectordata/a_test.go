@@ -0,0 +1,100 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"testing"
+)
+
+
+const areaTestSource = `(layers
+	(layer one
+		(menuitem "Look")
+		(features compound dot)
+	)
+)
+(feature compound
+	(polygon square
+		30.00 -83.00  30.00 -82.99
+		30.01 -82.99  30.01 -83.00)
+	(rectangle rect
+		30.00 -83.00  30.00 -82.99
+		30.01 -82.99  30.01 -83.00)
+	(circle well
+		(radius 100)
+		30.005 -82.995)
+	(path wall
+		30.00 -83.00  30.01 -83.00)
+)
+(circle dot
+	(pixels 10)
+	30.00 -83.00)
+(config
+	(lengthUnit furlong 201.168 meters)
+)
+`
+
+func compareTestAreas(T *testing.T, name string, want, got float64) {
+	T.Helper()
+	diff := want - got
+	if diff > 0.1 || diff < -0.1 {
+		T.Fatalf("%s: expected %.1f, got %.1f", name, want, got)
+	}
+}
+
+
+func Test_measureArea(T *testing.T) {
+	vd := prepareAndParseStrings(T, areaTestSource)
+	// 0.01 degree on a side at 30°N
+	square, squarePerimeter := 1071321.2, 4150.9
+	well, wellPerimeter := 31415.9, 628.3
+	for _, test := range []struct {
+		name string
+		squareMeters, perimeter float64
+		numItems int
+	}{
+		{"square", square, squarePerimeter, 1},
+		{"rect", square, squarePerimeter, 1},
+		{"well", well, wellPerimeter, 1},
+		{"compound", 2 * square + well, 2 * squarePerimeter + wellPerimeter, 3},
+	} {
+		measurement, err := vd.MeasureArea(test.name)
+		if err != nil {
+			T.Fatalf("%s: %s", test.name, err)
+		}
+		compareTestAreas(T, test.name, test.squareMeters, measurement.SquareMeters)
+		compareTestAreas(T, test.name + " perimeter", test.perimeter,
+			measurement.PerimeterMeters)
+		if measurement.NumItems != test.numItems {
+			T.Fatalf("%s: expected %d items, got %d", test.name, test.numItems,
+				measurement.NumItems)
+		}
+	}
+	for _, test := range []struct {
+		name, errmsg string
+	}{
+		{"dot", "infile0:20: circle has a radius in pixels, not meters"},
+		{"wall", "path wall contains no polygons, rectangles, or circles"},
+		{"nothing", "unknown map item 'nothing'"},
+	} {
+		_, err := vd.MeasureArea(test.name)
+		if err == nil || err.Error() != test.errmsg {
+			T.Fatalf("%s: expected error '%s', got '%v'", test.name, test.errmsg, err)
+		}
+	}
+	for _, test := range []struct {
+		unit string
+		squareMeters float64
+	}{
+		{"acres", 4046.9},
+		{"meters", 1},
+		{"furlong", 40468.6},
+	} {
+		got, err := vd.SquareMetersPerAreaUnit(test.unit)
+		if err != nil {
+			T.Fatalf("%s: %s", test.unit, err)
+		}
+		compareTestAreas(T, test.unit, test.squareMeters, got)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+
+// Paths by which a route crosses a river by the direct dry-season ford or by the wet-season ford
+// upstream
+const fordPaths = `
+	(path toRiver 30.0 -83.0 30.01 -83.0)
+	(path dryFord 30.01 -83.0 30.02 -83.0)
+	(path wetFord 30.02 -83.0 30.015 -83.01 30.01 -83.0)
+	(path strayFord 30.01 -83.0 30.015 -83.01)
+	(path fromRiver 30.02 -83.0 30.03 -83.0)
+	`
+
+const fordConfig = `(config (baseStyle wetStyle "color=#0000ff"))`
+
+
+func Test_alternatives(T *testing.T) {
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features camino wetFord strayFord)
+		)
+	)
+	(route camino
+		(segment (paths toRiver))
+		(point fordNorth 30.01 -83.0)
+		(alternatives
+			(alternative dry (paths dryFord))
+			(alternative wet (style wetStyle) (paths wetFord)))
+		(point fordSouth 30.02 -83.0)
+		(segment (paths fromRiver))
+	)` + fordPaths, fordConfig)
+	meters, err := vd.MeasurePath("camino")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "camino", 3336.8, meters)
+	meters, err = vd.MeasurePath("wet")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "wet", 2224.3, meters)
+	meters, err = vd.MeasureRouteWithAlternatives("camino", []string{"wet"})
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "camino by the wet ford", 3336.8 - 1112.3 + 2224.3, meters)
+	meters, err = vd.MeasureRouteWithAlternatives("camino", []string{"dry"})
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "camino by the dry ford", 3336.8, meters)
+
+	for _, tst := range []struct{names []string; errmsg string}{
+		{[]string{"toRiver"}, "'toRiver' is not an alternative in route 'camino'"},
+		{[]string{"dry", "wet"}, "alternatives 'dry' and 'wet' are in the same group"},
+	} {
+		_, err = vd.MeasureRouteWithAlternatives("camino", tst.names)
+		if err == nil || err.Error() != tst.errmsg {
+			T.Fatalf("expected error %s, got %v", tst.errmsg, err)
+		}
+	}
+
+	generated, err := vd.generateJson(FixedPointEncoding, nil)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	var doc map[string]any
+	err = json.Unmarshal([]byte(generated), &doc)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	features := doc["features"].([]any)
+	route := features[0].(map[string]any)
+	children := route["f"].([]any)
+	if len(children) != 4 {
+		T.Fatalf("expected 4 components of the route, got %d", len(children))
+	}
+	for i, want := range []struct{alt string; isDefault bool}{{"dry", true}, {"wet", false}} {
+		leg := features[int(children[i + 1].(float64))].(map[string]any)
+		checkAnyValue(T, leg["t"], "alternative type", "segment")
+		checkAnyValue(T, leg["alt"], "alternative name", want.alt)
+		checkAnyValue(T, leg["altGroup"], "alternative group", 1.0)
+		checkAnyValue(T, leg["altDefault"], "default alternative", want.isDefault)
+	}
+	if _, exists := features[int(children[0].(float64))].(map[string]any)["alt"]; exists {
+		T.Fatalf("segment which is not an alternative is marked as one")
+	}
+
+	geojson, err := vd.GenerateGeoJson()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if !strings.Contains(geojson, `"alternativeTo":"dry","layers":["Look"],"name":"wet"`) {
+		T.Fatalf("expected the wet alternative in the GeoJSON output, got %s", geojson)
+	}
+}
+
+
+func Test_alternativeErrors(T *testing.T) {
+	prepareAndParseStringsExpectingError(T, "deferred threading errors:\n" +
+		"infile0:12: alternative wet does not run between (30.010000,-83.000000) and " +
+		"(30.020000,-83.000000) as alternative dry does in route camino",
+		`(layers
+		(layer one
+			(menuitem "Look")
+			(features camino wetFord strayFord)
+		)
+	)
+	(route camino
+		(segment (paths toRiver))
+		(point fordNorth 30.01 -83.0)
+		(alternatives
+			(alternative dry (paths dryFord))
+			(alternative wet (style wetStyle) (paths strayFord)))
+		(point fordSouth 30.02 -83.0)
+		(segment (paths fromRiver))
+	)` + fordPaths, fordConfig)
+}
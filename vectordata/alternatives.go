@@ -0,0 +1,161 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+
+	"potano.misiones/sexp"
+)
+
+// A route may offer alternative legs between two of its waypoints, as a wet-season and a
+// dry-season ford.  The legs are listed in an (alternatives) list, each as a named
+// (alternative) list having the content of a segment.  The first is the default:  it takes
+// its place in the chain of the route's components and so in the route's threading and
+// length.  The others are threaded as segments by themselves and must run between the same
+// two points.
+
+
+type mapAlternativesType struct {
+	mapItemCore
+	members []*mapRouteOrSegmentType
+	number int				// position among the route's groups, from 1
+}
+
+// Marks a segment as an alternative leg
+type alternativeRef struct {
+	group *mapAlternativesType
+	index int
+}
+
+func newMapAlternatives(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	ma := &mapAlternativesType{}
+	ma.source = source
+	ma.name = parent.Name()
+	ma.itemType = mitAlternatives
+	return ma, nil
+}
+
+func (ma *mapAlternativesType) addFeature(feature mapItemType) {
+	member := feature.(*mapRouteOrSegmentType)
+	member.alternative = &alternativeRef{ma, len(ma.members)}
+	ma.members = append(ma.members, member)
+}
+
+
+func newMapAlternative(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	mr := &mapRouteOrSegmentType{}
+	mr.source = source
+	name, err := doc.registerMapItem(mr, listName)
+	mr.name = name
+	mr.itemType = mitSegment
+	return mr, err
+}
+
+
+func (ar *alternativeRef) name() string {
+	return ar.group.members[ar.index].Name()
+}
+
+func (ar *alternativeRef) isDefault() bool {
+	return ar.index == 0
+}
+
+
+// Reports alternatives which do not run between the points at which the default alternative
+// joins the route
+func (vd *VectorData) checkAlternatives(picked pickedItem) {
+	item := picked.item.(*mapRouteOrSegmentType)
+	for _, group := range item.alternatives {
+		defaultLeg := group.members[0]
+		var start, end latlongType
+		for _, child := range picked.children {
+			if child.item == defaultLeg {
+				start, end = child.startPoint, child.endPoint
+			}
+		}
+		for _, member := range group.members[1:] {
+			if (member.startPoint.samePoint(start) && member.endPoint.samePoint(end)) ||
+					(member.startPoint.samePoint(end) && member.endPoint.samePoint(start)) {
+				continue
+			}
+			vd.recordDeferredError(member.Error("alternative %s does not run between " +
+				"(%s,%s) and (%s,%s) as alternative %s does in %s %s", member.Name(),
+				start.lat, start.long, end.lat, end.long, defaultLeg.Name(),
+				item.ItemTypeString(), item.Name()))
+		}
+	}
+}
+
+
+// Measures a route taking the named alternatives in place of the default ones
+func (vd *VectorData) MeasureRouteWithAlternatives(routeName string,
+		alternativeNames []string) (float64, error) {
+	route, is := vd.mapItems[routeName].(*mapRouteOrSegmentType)
+	if !is {
+		if _, exists := vd.mapItems[routeName]; !exists {
+			return 0, fmt.Errorf("unknown map item '%s'", routeName)
+		}
+		return 0, fmt.Errorf("'%s' is not a route", routeName)
+	}
+	chosen := map[*mapAlternativesType]*mapRouteOrSegmentType{}
+	for _, name := range alternativeNames {
+		member, is := vd.mapItems[name].(*mapRouteOrSegmentType)
+		if !is || member.alternative == nil || !route.offersAlternatives(member.alternative) {
+			return 0, fmt.Errorf("'%s' is not an alternative in route '%s'", name,
+				routeName)
+		}
+		group := member.alternative.group
+		if other, exists := chosen[group]; exists {
+			return 0, fmt.Errorf("alternatives '%s' and '%s' are in the same group",
+				other.Name(), name)
+		}
+		chosen[group] = member
+	}
+	measurer := &simplePathMeasurer{earth: vd.earth}
+	for _, child := range route.children {
+		if leg, is := child.(*mapRouteOrSegmentType); is && leg.alternative != nil {
+			if member, exists := chosen[leg.alternative.group]; exists {
+				child = member
+			}
+		}
+		err := walkPathsForItem(measurer, child, false)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return measurer.meters, nil
+}
+
+func (mr *mapRouteOrSegmentType) offersAlternatives(ar *alternativeRef) bool {
+	for _, group := range mr.alternatives {
+		if group == ar.group {
+			return true
+		}
+	}
+	return false
+}
+
+
+// Lists the components of the route with each default alternative followed by the others of
+// its group
+func (mr *mapRouteOrSegmentType) componentsWithAlternatives() []mapItemType {
+	if len(mr.alternatives) == 0 {
+		return mr.children
+	}
+	var list []mapItemType
+	for _, child := range mr.children {
+		list = append(list, child)
+		if leg, is := child.(*mapRouteOrSegmentType); is && leg.alternative != nil &&
+				leg.alternative.isDefault() {
+			for _, member := range leg.alternative.group.members[1:] {
+				list = append(list, member)
+			}
+		}
+	}
+	return list
+}
+
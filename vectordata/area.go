@@ -0,0 +1,102 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+
+	"potano.misiones/great"
+)
+
+
+type areaMeasurement struct {
+	Name string
+	SquareMeters float64
+	PerimeterMeters float64
+	NumItems int		// number of polygons, rectangles, and circles measured
+	earth great.EarthModel
+}
+
+
+// Measures the area and perimeter of a polygon, rectangle, or circle or the total area and
+// perimeter of those items contained in a feature.  Overlapping items in a feature are not
+// accounted for.
+func (vd *VectorData) MeasureArea(itemName string) (areaMeasurement, error) {
+	measurement := areaMeasurement{Name: itemName, earth: vd.earth}
+	item, exists := vd.mapItems[itemName]
+	if !exists {
+		return measurement, fmt.Errorf("unknown map item '%s'", itemName)
+	}
+	err := measurement.addItem(item)
+	if err == nil && measurement.NumItems == 0 {
+		err = fmt.Errorf("%s %s contains no polygons, rectangles, or circles",
+			item.ItemTypeString(), itemName)
+	}
+	return measurement, err
+}
+
+
+func (am *areaMeasurement) addItem(item mapItemType) error {
+	switch item := item.(type) {
+	case *map_locationType:
+		switch item.ItemType() {
+		case mitPolygon:
+			ring := item.location.asFloatSlice()
+			am.SquareMeters += am.earth.SquareMetersInPolygon(ring)
+			am.PerimeterMeters += am.earth.MetersAroundPolygon(ring)
+		case mitRectangle:
+			corners := boundingRectangle(item.location.latlongPairs(false))
+			ring := make([]float64, 0, len(corners) << 1)
+			for _, ll := range corners {
+				ring = append(ring, float64(ll.lat) * latLongFixedToFloatMultiplier,
+					float64(ll.long) * latLongFixedToFloatMultiplier)
+			}
+			am.SquareMeters += am.earth.SquareMetersInPolygon(ring)
+			am.PerimeterMeters += am.earth.MetersAroundPolygon(ring)
+		case mitCircle:
+			if item.radiusType == mitPixels {
+				return item.Error("circle has a radius in pixels, not meters")
+			}
+			am.SquareMeters += am.earth.SquareMetersInCircle(float64(item.radius))
+			am.PerimeterMeters += am.earth.MetersAroundCircle(float64(item.radius))
+		default:
+			return nil
+		}
+		am.NumItems++
+	case *mapFeatureType:
+		return am.addItems(item.features)
+	case *map_referenceAggregateType:
+		return am.addItems(item.targets)
+	default:
+		return item.Error("cannot measure the area of a %s", item.ItemTypeString())
+	}
+	return nil
+}
+
+func (am *areaMeasurement) addItems(list []mapItemType) error {
+	for _, child := range list {
+		if _, is := child.(*mapRouteOrSegmentType); is {
+			continue
+		}
+		err := am.addItem(child)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+
+// Returns the number of square meters in the given unit of area:  acres or the square of a
+// length unit
+func (vd *VectorData) SquareMetersPerAreaUnit(unit string) (float64, error) {
+	if unit == "acre" || unit == "acres" {
+		return great.SQUARE_METERS_PER_ACRE, nil
+	}
+	metersPerUnit, exists := vd.lengthUnits[unit]
+	if !exists {
+		return 0, fmt.Errorf("unknown unit '%s'", unit)
+	}
+	return metersPerUnit * metersPerUnit, nil
+}
@@ -0,0 +1,218 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"potano.misiones/great"
+)
+
+
+const (
+	placeAtWaypoint = iota
+	placeNearCoordinates
+	placeAtDistance
+)
+
+// A place along a route, segment, or path:  a named waypoint, the point of the route nearest
+// a latitude/longitude pair, or a distance from the start of the route.  Points, markers, and
+// circles which are not waypoints of the route are located as latitude/longitude pairs.
+type RoutePlace struct {
+	kind int
+	name string
+	lat, long, meters float64
+}
+
+// Parses a waypoint name, a "latitude,longitude" pair, or a distance in the given unit
+func ParseRoutePlace(spec string, metersPerUnit float64) (RoutePlace, error) {
+	spec = strings.TrimSpace(spec)
+	if lat, long, found := strings.Cut(spec, ","); found {
+		latF, err := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+		if err == nil {
+			var longF float64
+			longF, err = strconv.ParseFloat(strings.TrimSpace(long), 64)
+			if err == nil && (latF < -90 || latF > 90 || longF < -180 || longF > 180) {
+				err = fmt.Errorf("out of range")
+			}
+			if err == nil {
+				return RoutePlace{kind: placeNearCoordinates, lat: latF, long: longF}, nil
+			}
+		}
+		return RoutePlace{}, fmt.Errorf("'%s' is not a valid latitude,longitude pair", spec)
+	}
+	if distance, err := strconv.ParseFloat(spec, 64); err == nil {
+		if distance < 0 {
+			return RoutePlace{}, fmt.Errorf("distance %s is negative", spec)
+		}
+		return RoutePlace{kind: placeAtDistance, meters: distance * metersPerUnit}, nil
+	}
+	if len(spec) == 0 {
+		return RoutePlace{}, fmt.Errorf("empty route place")
+	}
+	return RoutePlace{kind: placeAtWaypoint, name: spec}, nil
+}
+
+func (rp RoutePlace) String() string {
+	switch rp.kind {
+	case placeAtWaypoint:
+		return rp.name
+	case placeNearCoordinates:
+		return fmt.Sprintf("%.6f,%.6f", rp.lat, rp.long)
+	}
+	return fmt.Sprintf("%.1f meters", rp.meters)
+}
+
+
+
+type routeInterval struct {
+	FromMeters, ToMeters float64		// distances of the places from the start of the item
+	Meters float64				// distance between the places along the item
+	FromOffRoute, ToOffRoute float64	// meters from places off the item to the item
+}
+
+// Measures the distance between two places along the threaded course of a route, segment,
+// or path.  Along a loop the distance is that onward from the first place to the second, passing
+// the closing point if need be, and the places are located from the loop's start waypoint.
+func (vd *VectorData) MeasureBetween(itemName string, from, to RoutePlace) (routeInterval, error) {
+	start, total, isLoop, err := vd.measurementStart(itemName, nil)
+	if err != nil {
+		return routeInterval{}, err
+	}
+	indexer := &routeIndexer{earth: vd.earth, waypoints: map[string]float64{}}
+	err = vd.walkPathsForNamedItem(indexer, itemName, false)
+	if err != nil {
+		return routeInterval{}, err
+	}
+	var interval routeInterval
+	interval.FromMeters, interval.FromOffRoute, err = indexer.locate(vd, itemName, from)
+	if err == nil {
+		interval.ToMeters, interval.ToOffRoute, err = indexer.locate(vd, itemName, to)
+	}
+	if !isLoop || total == 0 {
+		interval.Meters = math.Abs(interval.ToMeters - interval.FromMeters)
+		return interval, err
+	}
+	// Distances given as places are already taken from the start of the loop
+	if from.kind != placeAtDistance {
+		interval.FromMeters = math.Mod(interval.FromMeters - start + total, total)
+	}
+	if to.kind != placeAtDistance {
+		interval.ToMeters = math.Mod(interval.ToMeters - start + total, total)
+	}
+	interval.Meters = interval.ToMeters - interval.FromMeters
+	if interval.Meters < 0 {
+		interval.Meters += total
+	}
+	return interval, err
+}
+
+
+// Records the distance from the start of the item to each vertex and waypoint
+type routeIndexer struct {
+	earth great.EarthModel
+	vertices []indexedVertex
+	waypoints map[string]float64
+	meters float64
+}
+
+type indexedVertex struct {
+	lat, long float64			// radians
+	meters float64
+	continues bool				// whether the step from the prior vertex is on the route
+}
+
+func (ri *routeIndexer) measurePath(path *map_locationType,
+		startOffset, endOffset locationIndexType) bool {
+	if path.isPoint() {
+		if _, seen := ri.waypoints[path.Name()]; !seen {
+			ri.waypoints[path.Name()] = ri.meters
+		}
+		return true
+	}
+	var pairs []float64
+	if endOffset < startOffset {
+		pairs = path.location.asReverseFloatSlice()
+	} else {
+		pairs = path.location.asFloatSlice()
+	}
+	steps := ri.earth.MetersBetweenPointPairs(pairs)
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			ri.meters += steps[(i >> 1) - 1]
+		}
+		ri.vertices = append(ri.vertices, indexedVertex{
+			lat: pairs[i] * great.DEG_TO_RADIANS,
+			long: pairs[i+1] * great.DEG_TO_RADIANS,
+			meters: ri.meters,
+			continues: i > 0,
+		})
+	}
+	return true
+}
+
+
+// Returns the distance of the place from the start of the item and, for coordinates, the
+// distance from the coordinates to the item
+func (ri *routeIndexer) locate(vd *VectorData, itemName string, place RoutePlace) (float64,
+		float64, error) {
+	switch place.kind {
+	case placeAtDistance:
+		if place.meters > ri.meters {
+			item := vd.mapItems[itemName]
+			return 0, 0, fmt.Errorf("%s '%s' is only %.1f meters (%.2f miles) long",
+				item.ItemTypeString(), itemName, ri.meters,
+				ri.meters / great.METERS_PER_MILE)
+		}
+		return place.meters, 0, nil
+	case placeAtWaypoint:
+		if meters, found := ri.waypoints[place.name]; found {
+			return meters, 0, nil
+		}
+		item, exists := vd.mapItems[place.name]
+		if !exists {
+			return 0, 0, fmt.Errorf("unknown map item '%s'", place.name)
+		}
+		loc, is := item.(*map_locationType)
+		if !is || !loc.isPoint() {
+			return 0, 0, fmt.Errorf("%s '%s' is not a waypoint", item.ItemTypeString(),
+				place.name)
+		}
+		// A waypoint not on the item, such as a mission set back from the road
+		pairs := loc.location.asFloatSlice()
+		meters, offRoute := ri.nearest(pairs[0], pairs[1])
+		return meters, offRoute, nil
+	}
+	meters, offRoute := ri.nearest(place.lat, place.long)
+	return meters, offRoute, nil
+}
+
+
+// Finds the point of the item nearest the given latitude and longitude in degrees.  Returns its
+// distance from the start of the item and its distance from the given point.
+func (ri *routeIndexer) nearest(lat, long float64) (float64, float64) {
+	lat *= great.DEG_TO_RADIANS
+	long *= great.DEG_TO_RADIANS
+	bestMeters, bestOffRoute := 0.0, math.Inf(1)
+	for i, vertex := range ri.vertices {
+		var meters, offRoute float64
+		if vertex.continues {
+			prev := ri.vertices[i-1]
+			var fraction float64
+			offRoute, fraction = ri.earth.NearestOnSegment(lat, long, prev.lat, prev.long,
+				vertex.lat, vertex.long)
+			meters = prev.meters + fraction * (vertex.meters - prev.meters)
+		} else {
+			offRoute = ri.earth.MetersBetweenPoints(lat, long, vertex.lat, vertex.long)
+			meters = vertex.meters
+		}
+		if offRoute < bestOffRoute {
+			bestMeters, bestOffRoute = meters, offRoute
+		}
+	}
+	return bestMeters, bestOffRoute
+}
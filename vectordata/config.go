@@ -36,6 +36,16 @@ func (mc *mapConfigType) setConfigurationItem(newChild mapItemType) error {
 		return mc.doc.attester.setAttestationType(item)
 	case *mapLengthUnitType:
 		return mc.doc.setLengthUnit(item)
+	case *mapGeojsonKeysType:
+		return mc.doc.setGeojsonKeys(item)
+	case *mapEarthModelType:
+		return mc.doc.setEarthModel(item)
+	case *mapTravelRateType:
+		return mc.doc.setTravelRate(item)
+	case *mapSnapToleranceType:
+		return mc.doc.setSnapTolerance(item)
+	case *mapCrossingCheckType:
+		return mc.doc.setCrossingCheck(item)
 	default:
 		return newChild.Error("unknown config target name")
 	}
@@ -241,6 +251,129 @@ func (ml *mapLengthUnitType) addScalars(targetName string, scalars []sexp.LispSc
 
 
 
+type mapGeojsonKeysType struct {
+	mapItemCore
+	keys map[string]string
+}
+
+func newMapGeojsonKeys(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	mg := &mapGeojsonKeysType{keys: map[string]string{}}
+	mg.source = source
+	mg.itemType = mitGeojsonKeys
+	return mg, nil
+}
+
+func (mg *mapGeojsonKeysType) addScalars(targetName string, scalars []sexp.LispScalar) error {
+	for _, scalar := range scalars {
+		key, value, err := decomposeKeyValueScalar(scalar)
+		if err != nil {
+			return err
+		}
+		if _, exists := mg.keys[key]; exists {
+			return scalar.Error("duplicate GeoJSON key mapping for %s", key)
+		}
+		mg.keys[key] = string(value)
+	}
+	return nil
+}
+
+
+
+
+
+
+
+type mapEarthModelType struct {
+	mapItemCore
+	modelName string
+	parameters []float64
+}
+
+func newMapEarthModel(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	me := &mapEarthModelType{}
+	me.source = source
+	me.itemType = mitEarthModel
+	return me, nil
+}
+
+func (me *mapEarthModelType) addScalars(targetName string, scalars []sexp.LispScalar) error {
+	if targetName == "modelName" {
+		me.modelName = scalars[0].String()
+		return nil
+	}
+	for _, scalar := range scalars {
+		f, err := strconv.ParseFloat(scalar.String(), 64)
+		if err != nil {
+			return scalar.Error("%s", err)
+		}
+		me.parameters = append(me.parameters, f)
+	}
+	return nil
+}
+
+
+
+
+
+
+
+type mapSnapToleranceType struct {
+	mapItemCore
+	meters float64
+	mode string
+}
+
+func newMapSnapTolerance(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	ms := &mapSnapToleranceType{}
+	ms.source = source
+	ms.itemType = mitSnapTolerance
+	return ms, nil
+}
+
+func (ms *mapSnapToleranceType) addScalars(targetName string, scalars []sexp.LispScalar) error {
+	if targetName == "mode" {
+		ms.mode = scalars[0].String()
+		return nil
+	}
+	meters, err := strconv.ParseFloat(scalars[0].String(), 64)
+	if err != nil {
+		return scalars[0].Error("%s", err)
+	}
+	ms.meters = meters
+	return nil
+}
+
+
+
+
+
+
+type mapCrossingCheckType struct {
+	mapItemCore
+	mode string
+}
+
+func newMapCrossingCheck(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	mc := &mapCrossingCheckType{}
+	mc.source = source
+	mc.itemType = mitCrossingCheck
+	return mc, nil
+}
+
+func (mc *mapCrossingCheckType) addScalars(targetName string, scalars []sexp.LispScalar) error {
+	mc.mode = scalars[0].String()
+	return nil
+}
+
+
+
+
+
+
 type mapAttSymType struct {
 	mapItemCore
 	hasWeight bool
@@ -6,6 +6,8 @@ package vectordata
 import (
 	"fmt"
 	"sort"
+
+	"potano.misiones/great"
 )
 
 type locationIndexType int32
@@ -30,6 +32,16 @@ type latlongRefs []latlongRef
 type crossingFinderType struct {
 	crossingFinderChannel chan locationPathsRecord
 	crossingsDoneChannel chan map[locationIndexType]latlongRefs
+
+	// Settings must be made before input ends; results are ready when crosspoints are
+	snapTolerance float64
+	snapMerge bool
+	earth great.EarthModel
+	nearPoints []nearPointPair
+	substitutions map[latlongType]latlongType
+	crossingCheck int
+	crossings []geometricCrossing
+	insertedVertices map[locationIndexType]locationPairs
 }
 
 func newCrossingFinder() *crossingFinderType {
@@ -47,7 +59,8 @@ func (cf *crossingFinderType) addLocation(locationIndex, startIndex locationInde
 	cf.crossingFinderChannel <- locationPathsRecord{locationIndex, startIndex, pairs}
 }
 
-func (cf *crossingFinderType) signalNoMoreInput() {
+func (cf *crossingFinderType) signalNoMoreInput(earth great.EarthModel) {
+	cf.earth = earth
 	close(cf.crossingFinderChannel)
 }
 
@@ -62,12 +75,30 @@ type cpathInfo struct {
 }
 
 func findCrosspoints(cf *crossingFinderType) {
-	allPoints := map[latlongType][]cpathInfo{}
+	var records []locationPathsRecord
 	for {
 		locpath, ok := <- cf.crossingFinderChannel
 		if !ok {
 			break
 		}
+		records = append(records, locpath)
+	}
+
+	if cf.crossingCheck != noCrossingCheck {
+		locations := map[locationIndexType]locationPairs{}
+		for _, locpath := range records {
+			locations[locpath.locationIndex] = append(locations[locpath.locationIndex],
+				locpath.pairs...)
+		}
+		cf.crossings = findGeometricCrossings(locations)
+		if cf.crossingCheck == insertCrossingVertices {
+			cf.insertedVertices = insertVerticesAtCrossings(locations, cf.crossings)
+			records = replaceLocationRecords(records, cf.insertedVertices)
+		}
+	}
+
+	allPoints := map[latlongType][]cpathInfo{}
+	for _, locpath := range records {
 		lastIndex := len(locpath.pairs) - 2
 		for i := 0; i <= lastIndex; i += 2 {
 			pair := locpath.pairs.latlongPair(i)
@@ -87,6 +118,13 @@ func findCrosspoints(cf *crossingFinderType) {
 		}
 	}
 
+	if cf.snapTolerance > 0 {
+		cf.nearPoints = findNearPoints(allPoints, cf.snapTolerance, cf.earth)
+		if cf.snapMerge {
+			cf.substitutions = mergeNearPoints(allPoints, cf.nearPoints)
+		}
+	}
+
 	locationCrosspoints := map[locationIndexType]latlongRefs{}
 
 	for point, cpiList := range allPoints {
@@ -111,6 +149,23 @@ func findCrosspoints(cf *crossingFinderType) {
 
 
 
+// Replaces all the records of each modified location with one record of its new vertices
+func replaceLocationRecords(records []locationPathsRecord,
+		modified map[locationIndexType]locationPairs) []locationPathsRecord {
+	var out []locationPathsRecord
+	for _, locpath := range records {
+		pairs, found := modified[locpath.locationIndex]
+		if !found {
+			out = append(out, locpath)
+		} else if locpath.startOffset == 0 {
+			out = append(out, locationPathsRecord{locpath.locationIndex, 0, pairs})
+		}
+	}
+	return out
+}
+
+
+
 func (ll1 latlongRef) comesBefore(other latlongRef) bool {
 	return ll1.indices[0] < other.indices[0] ||
 		(ll1.indices[0] == other.indices[0] &&
@@ -177,6 +232,28 @@ func (llrs latlongRefs) strings() []string {
 	return out
 }
 
+// Returns the references at points which any of the others share
+func (llrs latlongRefs) sharedWith(others latlongRefs) latlongRefs {
+	var shared latlongRefs
+	for _, ref := range llrs {
+		for _, other := range others {
+			if ref.point.samePoint(other.point) {
+				shared = append(shared, ref)
+				break
+			}
+		}
+	}
+	return shared
+}
+
+func (llrs latlongRefs) points() []latlongType {
+	out := make([]latlongType, len(llrs))
+	for i, r := range llrs {
+		out[i] = r.point
+	}
+	return out
+}
+
 func (llrs latlongRefs) sort() {
 	if len(llrs) > 1 {
 		sort.Slice(llrs, func (i, j int) bool {
@@ -216,9 +293,10 @@ func synthesizeCrosspointEndReference(item threadableMapItemType, makeStartpoint
 		} else {
 			item = rs.children[useOffset].(threadableMapItemType)
 		}
-		var itemStartPoint latlongType
-		itemStartPoint, _, altOffset, useOffset = item.endpointsAndOffsets()
-		if itemStartPoint == usePoint {
+		var itemStartPoint, itemEndPoint latlongType
+		itemStartPoint, itemEndPoint, altOffset, useOffset = item.endpointsAndOffsets()
+		// Both ends of a closed item are at the point; keep the end wanted
+		if itemStartPoint == usePoint && (makeStartpoint || itemEndPoint != usePoint) {
 			useOffset = altOffset
 		}
 		ref.indices[depth] = useOffset
@@ -31,7 +31,20 @@ func (c cssPropertyValue) jsonForm() string {
 	if len(parseCssValueRegex.FindString(string(c))) > 0 {
 		return string(c)
 	}
-	return strconv.Quote(string(c))
+	return jsonQuote(string(c))
+}
+
+// Converts the value to a bool, float64, or string for use with encoding/json
+func (c cssPropertyValue) typedValue() any {
+	if len(parseCssValueRegex.FindString(string(c))) > 0 {
+		if b, err := c.asBool(); err == nil {
+			return b
+		}
+		if f, err := c.asFloat(); err == nil {
+			return f
+		}
+	}
+	return string(c)
 }
 
 var parseCssValueRegex *regexp.Regexp = regexp.MustCompile(
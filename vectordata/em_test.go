@@ -0,0 +1,123 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+
+const earthModelRoute = `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(segment
+			(paths path1 path2)
+		)
+	)
+	` + path1 + path2
+
+
+func Test_earthModelFromConfig(T *testing.T) {
+	vd := prepareAndParseStrings(T, earthModelRoute, `(config (earthModel wgs84))`)
+	if vd.EarthModel() != "wgs84" {
+		T.Fatalf("expected wgs84 earth model, got %s", vd.EarthModel())
+	}
+	const path1Wgs84 = 979.569408
+	const path2Wgs84 = 258.228563
+	for _, test := range []struct{name string; meters float64} {
+		{"path1", path1Wgs84},
+		{"path2", path2Wgs84},
+		{"theRoad", path1Wgs84 + path2Wgs84},
+	} {
+		distance, err := vd.MeasurePath(test.name)
+		if err != nil {
+			T.Fatalf("error measuring %s: %s", test.name, err)
+		}
+		compareTestLengths(T, test.name, test.meters, distance)
+	}
+	lat, long, distance, pathName, index, err := vd.MeasurePathUpTo("theRoad", 1000)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestUpTo(T, 30.351541, -83.517636, path1Wgs84, "path2", 0,
+		lat, long, distance, pathName, index)
+
+	err = vd.SetEarthModel("sphere", nil)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	distance, err = vd.MeasurePath("theRoad")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "theRoad", path1_length + path2_length, distance)
+}
+
+
+func Test_earthModelErrors(T *testing.T) {
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(earthModelRoute),
+		strings.NewReader(`(config (earthModel flat))`)},
+		"infile1:1: unknown earth model flat; expected sphere, wgs84, or ellipsoid")
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(earthModelRoute),
+		strings.NewReader(`(config (earthModel wgs84) (earthModel sphere))`)},
+		"infile1:1: earth model already set")
+	vd := prepareAndParseStrings(T, earthModelRoute)
+	if vd.EarthModel() != "sphere" {
+		T.Fatalf("expected sphere earth model by default, got %s", vd.EarthModel())
+	}
+	if err := vd.SetEarthModel("clarke1866", nil); err == nil {
+		T.Fatalf("expected error setting unknown earth model")
+	}
+}
+
+
+func Test_earthModelParameters(T *testing.T) {
+	square := `(feature grounds
+		(polygon 30.0 -83.0 30.0 -82.99 30.01 -82.99 30.01 -83.0)
+	)`
+	for _, test := range []struct{config, model string; road, area float64} {
+		{"(earthModel sphere 6371000)", "sphere 6371000", 1235.519, 1070726.85},
+		{"(earthModel ellipsoid 6378206.4 294.9786982)",
+			"ellipsoid 6378206.4 294.9786982", 1237.835, 1070725.9},
+	} {
+		vd := prepareAndParseStrings(T, `(layers
+			(layer one
+				(menuitem "Look")
+				(features theRoad grounds)
+			)
+		)
+		(route theRoad
+			(segment
+				(paths path1 path2)
+			)
+		)
+		` + path1 + path2 + square, "(config " + test.config + ")")
+		if vd.EarthModel() != test.model {
+			T.Fatalf("expected earth model %s, got %s", test.model, vd.EarthModel())
+		}
+		distance, err := vd.MeasurePath("theRoad")
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		compareTestLengths(T, test.model, test.road, distance)
+		measurement, err := vd.MeasureArea("grounds")
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		compareTestLengths(T, test.model, test.area, measurement.SquareMeters)
+	}
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(earthModelRoute),
+		strings.NewReader(`(config (earthModel sphere 6371))`)},
+		"infile1:1: sphere radius 6371 is not between 6300000 and 6400000 meters")
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(earthModelRoute),
+		strings.NewReader(`(config (earthModel ellipsoid 6378137))`)},
+		"infile1:1: an ellipsoid takes two parameters, " +
+		"the semi-major axis and the inverse flattening")
+}
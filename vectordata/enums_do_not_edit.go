@@ -29,6 +29,8 @@ const (
 	mitRadius
 	mitPixels
 	mitSegment
+	mitAlternatives
+	mitAlternative
 	mitSegments
 	mitConfig
 	mitBaseStyle
@@ -36,6 +38,15 @@ const (
 	mitAttSym
 	mitModStyle
 	mitLengthUnit
+	mitGeojsonKeys
+	mitMileposts
+	mitPeriod
+	mitLoop
+	mitEarthModel
+	mitTravelRate
+	mitTravel
+	mitSnapTolerance
+	mitCrossingCheck
 )
 
 var nameToTypeMap map[string]int = map[string]int{
@@ -61,6 +72,8 @@ var nameToTypeMap map[string]int = map[string]int{
 	"radius":      mitRadius,
 	"pixels":      mitPixels,
 	"segment":     mitSegment,
+	"alternatives": mitAlternatives,
+	"alternative": mitAlternative,
 	"segments":    mitSegments,
 	"config":      mitConfig,
 	"baseStyle":   mitBaseStyle,
@@ -68,6 +81,15 @@ var nameToTypeMap map[string]int = map[string]int{
 	"attSym":      mitAttSym,
 	"modStyle":    mitModStyle,
 	"lengthUnit":  mitLengthUnit,
+	"geojsonKeys": mitGeojsonKeys,
+	"mileposts":   mitMileposts,
+	"period":      mitPeriod,
+	"loop":        mitLoop,
+	"earthModel":  mitEarthModel,
+	"travelRate":  mitTravelRate,
+	"travel":      mitTravel,
+	"snapTolerance": mitSnapTolerance,
+	"crossingCheck": mitCrossingCheck,
 }
 
 var typeMapToName []string = []string{
@@ -94,6 +116,8 @@ var typeMapToName []string = []string{
 	"radius",
 	"pixels",
 	"segment",
+	"alternatives",
+	"alternative",
 	"segments",
 	"config",
 	"baseStyle",
@@ -101,4 +125,13 @@ var typeMapToName []string = []string{
 	"attSym",
 	"modStyle",
 	"lengthUnit",
+	"geojsonKeys",
+	"mileposts",
+	"period",
+	"loop",
+	"earthModel",
+	"travelRate",
+	"travel",
+	"snapTolerance",
+	"crossingCheck",
 }
@@ -0,0 +1,220 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"strings"
+)
+
+// When a route or segment fails to thread or takes a path in an unexpected direction, the
+// deferred error alone seldom tells why.  The threading explainer follows the threading of a
+// single named route or segment, noting the crosspoints each component offers, how the start
+// and end of each component were chosen, whether the component was reversed, and the offsets
+// finally taken.
+
+
+type threadingExplainer struct {
+	name string
+	found bool
+	lines []string
+	startNotes, endNotes [][]string
+}
+
+
+// Requests an explanation of the threading of the named route or segment.  Must be called
+// before CheckAndReformRoutes.
+func (vd *VectorData) SetExplainThreading(name string) {
+	vd.threadingExplainer = &threadingExplainer{name: name}
+}
+
+
+// Returns the explanation of the threading of the route or segment named in
+// SetExplainThreading
+func (vd *VectorData) ThreadingExplanation() (string, error) {
+	te := vd.threadingExplainer
+	if te == nil {
+		return "", fmt.Errorf("no threading explanation was requested")
+	}
+	item, exists := vd.mapItems[te.name]
+	if !exists {
+		return "", fmt.Errorf("unknown map item '%s'", te.name)
+	}
+	if _, is := item.(*mapRouteOrSegmentType); !is {
+		return "", fmt.Errorf("%s '%s' is not a route or segment", item.ItemTypeString(),
+			te.name)
+	}
+	if !te.found {
+		return "", fmt.Errorf("%s '%s' was not threaded", item.ItemTypeString(), te.name)
+	}
+	return strings.Join(te.lines, "\n") + "\n", nil
+}
+
+
+// Returns the explainer if the item is the one to be explained or an anonymous segment within
+// it; otherwise nil.  All explainer methods do nothing when called on nil.
+func (vd *VectorData) explainerFor(item mapItemType) *threadingExplainer {
+	te := vd.threadingExplainer
+	if te == nil || !vd.isOrIsWithin(item, te.name) {
+		return nil
+	}
+	te.found = true
+	return te
+}
+
+func (vd *VectorData) isOrIsWithin(item mapItemType, name string) bool {
+	if item.Name() == name {
+		return true
+	}
+	if len(exportableName(item)) > 0 {
+		return false
+	}
+	for _, referrer := range item.Referrers() {
+		if parent, exists := vd.mapItems[referrer]; exists && vd.isOrIsWithin(parent, name) {
+			return true
+		}
+	}
+	return false
+}
+
+
+func (te *threadingExplainer) printf(format string, args ...any) {
+	te.lines = append(te.lines, fmt.Sprintf(format, args...))
+}
+
+
+func (te *threadingExplainer) begin(item mapItemType, children []threadableMapItemType) {
+	if te == nil {
+		return
+	}
+	if len(te.lines) > 0 {
+		te.printf("")
+	}
+	te.printf("threading %s (%s)", describeComponent(item), item.Source().SourceDescription())
+	te.startNotes = make([][]string, len(children))
+	te.endNotes = make([][]string, len(children))
+}
+
+
+func (te *threadingExplainer) noteStart(childX int, format string, args ...any) {
+	if te == nil {
+		return
+	}
+	te.startNotes[childX] = append(te.startNotes[childX], fmt.Sprintf(format, args...))
+}
+
+func (te *threadingExplainer) noteEnd(childX int, format string, args ...any) {
+	if te == nil {
+		return
+	}
+	te.endNotes[childX] = append(te.endNotes[childX], fmt.Sprintf(format, args...))
+}
+
+
+// Describes each component's candidate crosspoints, the choice of its start and end, its
+// direction, and the part of it taken
+func (te *threadingExplainer) explainPicks(children []threadableMapItemType,
+		marked []pendingChildInfo, picked pickedItem) {
+	if te == nil {
+		return
+	}
+	for childX, pending := range marked {
+		child := children[childX]
+		te.printf("%d. %s (%s)", childX + 1, describeComponent(child),
+			child.Source().SourceDescription())
+		crosspoints := child.getCrosspoints()
+		te.printf("   candidate crosspoints: %s", describeRefs(child, crosspoints))
+		for _, note := range te.startNotes[childX] {
+			te.printf("   start: %s", note)
+		}
+		te.printf("   chose start %s", describeRef(child, pending.startRefs[0]))
+		for _, note := range te.endNotes[childX] {
+			te.printf("   end: %s", note)
+		}
+		te.printf("   chose end %s", describeRef(child, pending.endRefs[0]))
+		if pending.endRefs[0].comesBefore(pending.startRefs[0]) {
+			te.printf("   reversed: its chosen end comes before its chosen start in its " +
+				"own order")
+		} else if !child.isPoint() {
+			te.printf("   taken in its own direction")
+		}
+		if childX >= len(picked.children) {
+			continue
+		}
+		taken := picked.children[childX]
+		shortened := ""
+		if taken.shortened {
+			shortened = ", shortened"
+		}
+		te.printf("   offsets %d to %d, from (%s,%s) to (%s,%s)%s", taken.startOffset,
+			taken.endOffset, taken.startPoint.lat, taken.startPoint.long,
+			taken.endPoint.lat, taken.endPoint.long, shortened)
+	}
+	te.printf("%s runs from (%s,%s) to (%s,%s)", describeComponent(picked.item),
+		picked.startPoint.lat, picked.startPoint.long,
+		picked.endPoint.lat, picked.endPoint.long)
+}
+
+
+func (te *threadingExplainer) explainErrors(errs []error) {
+	if te == nil {
+		return
+	}
+	for _, err := range errs {
+		te.printf("error: %s", err.Error())
+	}
+}
+
+
+
+func describeComponent(item mapItemType) string {
+	if len(exportableName(item)) == 0 {
+		return "anonymous " + item.ItemTypeString()
+	}
+	return fmt.Sprintf("%s '%s'", item.ItemTypeString(), item.Name())
+}
+
+
+// Describes a crosspoint as its coordinates and the vertex of the path at which it lies
+func describeRef(child threadableMapItemType, ref latlongRef) string {
+	where := fmt.Sprintf("(%s,%s)", ref.point.lat, ref.point.long)
+	loc := child.resolveReferenceToLocation(ref)
+	if loc == nil {
+		return where
+	}
+	if loc.isPoint() {
+		return fmt.Sprintf("%s, %s", where, describeComponent(loc))
+	}
+	var item mapItemType = child
+	if tmir, is := item.(*threadableMapItemReference); is {
+		item = tmir.item
+	}
+	level := 0
+	for route, is := item.(*mapRouteOrSegmentType); is && level < len(ref.indices) - 1;
+			route, is = item.(*mapRouteOrSegmentType) {
+		item = route.children[ref.indices[level]]
+		level++
+	}
+	return fmt.Sprintf("%s, point %d of %s", where, ref.indices[level] / 2 + 1,
+		describeComponent(loc))
+}
+
+func describeRefs(child threadableMapItemType, refs latlongRefs) string {
+	if len(refs) == 0 {
+		return "none"
+	}
+	described := make([]string, len(refs))
+	for i, ref := range refs {
+		described[i] = describeRef(child, ref)
+	}
+	return strings.Join(described, "; ")
+}
+
+
+func firstOrLast(last bool) string {
+	if last {
+		return "last"
+	}
+	return "first"
+}
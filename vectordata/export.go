@@ -0,0 +1,203 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+// Flattens the map-item tree for export to formats which, unlike the generated Javascript, have
+// no notion of features inheriting popups, styles, and attestations from their parents.  Routes
+// and segments are reduced to the lines of their threaded traversals.
+
+
+type exportLayer struct {
+	name, menuitem string
+	items []*exportItem
+}
+
+type exportItem struct {
+	item mapItemType
+	layers []string				// menuitems of layers containing the item
+	popup string
+	style cssPropertyMap
+	attestations []string
+	points []latlongType			// markers, circles, polygons, and rectangles
+	lines [][]latlongType			// paths, routes, and segments
+}
+
+// Attributes in force at the current level of the tree
+type exportContext struct {
+	popup string
+	style cssPropertyMap
+	attestations []string
+}
+
+type exporter struct {
+	vd *VectorData
+	layers []*exportLayer
+	itemsByName map[string]*exportItem
+}
+
+
+func (vd *VectorData) flattenForExport() ([]*exportLayer, error) {
+	if vd.layersRoot == nil {
+		return nil, nil
+	}
+	if vd.styler != nil && !vd.styler.styleCheckRun() {
+		err := vd.CheckInStylesAndAttestations()
+		if err != nil {
+			return nil, err
+		}
+	}
+	ex := &exporter{vd: vd, itemsByName: map[string]*exportItem{}}
+	for _, layer := range vd.layersRoot.layers {
+		el := &exportLayer{name: layer.Name(), menuitem: layer.menuitem}
+		ex.layers = append(ex.layers, el)
+		err := ex.addFeatures(el, layer.features, exportContext{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ex.layers, nil
+}
+
+
+func (ex *exporter) addFeatures(layer *exportLayer, list []mapItemType, ctx exportContext) error {
+	for _, child := range list {
+		if !ex.vd.isActive(child) {
+			continue
+		}
+		var err error
+		switch child := child.(type) {
+		case *map_referenceAggregateType:
+			err = ex.addFeatures(layer, child.targets, ctx)
+		case *mapFeatureType:
+			err = ex.addFeatures(layer, child.features, ex.applyAttributes(ctx, child))
+		case *mapRouteOrSegmentType:
+			err = ex.addRouteOrSegment(layer, child, ex.applyAttributes(ctx, child))
+		case *map_locationType:
+			if child.ItemType() != mitPoint {
+				ex.addLocation(layer, child, ex.applyAttributes(ctx, child))
+			}
+		default:
+			err = child.Error("unhandled item type %s", child.ItemTypeString())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+
+func (ex *exporter) addRouteOrSegment(layer *exportLayer, item *mapRouteOrSegmentType,
+		ctx exportContext) error {
+	collector := &traversalCollector{}
+	err := walkPathsForItem(collector, item, false)
+	if err != nil {
+		return err
+	}
+	if ei := ex.addItem(layer, item, ctx); ei != nil {
+		ei.lines = collector.lines
+	}
+	for _, waypoint := range collector.waypoints {
+		if waypoint.ItemType() != mitPoint {
+			ex.addLocation(layer, waypoint, ex.applyAttributes(ctx, waypoint))
+		}
+	}
+	if item.mileposts != nil {
+		for _, marker := range item.mileposts.markers {
+			ex.addLocation(layer, marker, ex.applyAttributes(ctx, marker))
+		}
+	}
+	for _, group := range item.alternatives {
+		for _, member := range group.members[1:] {
+			if !ex.vd.isActive(member) {
+				continue
+			}
+			err = ex.addRouteOrSegment(layer, member, ex.applyAttributes(ctx, member))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+
+func (ex *exporter) addLocation(layer *exportLayer, item *map_locationType, ctx exportContext) {
+	ei := ex.addItem(layer, item, ctx)
+	if ei == nil {
+		return
+	}
+	if item.ItemType() == mitPath {
+		ei.lines = [][]latlongType{item.location.latlongPairs(false)}
+	} else {
+		ei.points = item.location.latlongPairs(false)
+	}
+}
+
+
+// Returns nil if the item has already been seen in this layer
+func (ex *exporter) addItem(layer *exportLayer, item mapItemType, ctx exportContext) *exportItem {
+	ei, exists := ex.itemsByName[item.Name()]
+	if exists {
+		for _, menuitem := range ei.layers {
+			if menuitem == layer.menuitem {
+				return nil
+			}
+		}
+		ei.layers = append(ei.layers, layer.menuitem)
+		layer.items = append(layer.items, ei)
+		return nil
+	}
+	ei = &exportItem{
+		item: item,
+		layers: []string{layer.menuitem},
+		popup: ctx.popup,
+		style: ctx.style,
+		attestations: ctx.attestations,
+	}
+	ex.itemsByName[item.Name()] = ei
+	layer.items = append(layer.items, ei)
+	return ei
+}
+
+
+func (ex *exporter) applyAttributes(ctx exportContext, item mapItemType) exportContext {
+	if popup := popupText(item); len(popup) > 0 {
+		ctx.popup = popup
+	}
+	if props := ex.vd.styler.resolvedProperties(item); props != nil {
+		ctx.style = props
+	}
+	if _, attestation := item.styleAndAttestation(); attestation != nil {
+		ctx.attestations = attestation.attestations
+	}
+	return ctx
+}
+
+
+// Names assigned internally to anonymous items are of no use outside the program
+func exportableName(item mapItemType) string {
+	name := item.Name()
+	if len(name) == 0 || name[0] == '$' {
+		return ""
+	}
+	return name
+}
+
+
+func popupText(item mapItemType) string {
+	var popup *mapPopupType
+	switch item := item.(type) {
+	case *mapFeatureType:
+		popup = item.popup
+	case *mapRouteOrSegmentType:
+		popup = item.popup
+	case *map_locationType:
+		popup = item.popup
+	}
+	if popup == nil {
+		return ""
+	}
+	return popup.text
+}
@@ -0,0 +1,162 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"potano.misiones/great"
+)
+
+
+// Stretch of a single path along a found route
+type routePiece struct {
+	path *map_locationType
+	from, to networkSplit
+}
+
+// Greatest distance in meters of a waypoint from a path for it to be taken as lying on the path
+const onPathMeters = 1.0
+
+// Finds the shortest connection along the network of paths between two waypoints and writes
+// it as the source text of a route of the given name.  The waypoints may lie between the
+// vertices of a path.  If any names are given in the within list, only the paths contained in
+// the named layers, features, routes, segments, or paths are used.  Returns the route text and
+// its length in meters.
+func (vd *VectorData) FindRoute(routeName, from, to string, within []string) (string, float64,
+		error) {
+	paths := vd.networkPaths()
+	if len(within) > 0 {
+		var err error
+		paths, err = vd.pathsWithin(paths, within)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+	var anchors []networkAnchor
+	for _, name := range []string{from, to} {
+		anchor, err := vd.anchorPlace(name, paths)
+		if err != nil {
+			return "", 0, err
+		}
+		if anchor.offNetwork > onPathMeters {
+			return "", 0, fmt.Errorf("%s '%s' is not on any path",
+				vd.mapItems[name].ItemTypeString(), name)
+		}
+		if anchor.at.fraction > 0 {
+			// Have the route's own path end exactly at the waypoint
+			anchor.at.point = vd.mapItems[name].(*map_locationType).pointAtOffset(0)
+		}
+		anchors = append(anchors, anchor)
+	}
+	pn := vd.buildPathNetwork(paths, anchors)
+	start := pn.nodeIndex[anchors[0].at.point]
+	end := pn.nodeIndex[anchors[1].at.point]
+	if start == end {
+		return "", 0, fmt.Errorf("'%s' and '%s' are at the same place", from, to)
+	}
+	distances, arrivals := pn.shortestFrom(start)
+	if math.IsInf(distances[end], 1) {
+		return "", 0, fmt.Errorf("no paths connect '%s' and '%s'", from, to)
+	}
+
+	// Collect the edges from the end back to the start, then merge those along the same path
+	var edges []*networkEdge
+	for node := end; node != start; {
+		edge := arrivals[node]
+		edges = append(edges, edge)
+		node = pn.nodeIndex[edge.fromSplit.point]
+	}
+	var pieces []routePiece
+	for i := len(edges) - 1; i >= 0; i-- {
+		edge := edges[i]
+		if n := len(pieces); n > 0 && pieces[n-1].path == edge.path {
+			pieces[n-1].to = edge.toSplit
+		} else {
+			pieces = append(pieces, routePiece{edge.path, edge.fromSplit, edge.toSplit})
+		}
+	}
+	return formatFoundRoute(routeName, from, to, pieces, distances[end]), distances[end], nil
+}
+
+
+// Filters the paths to those lying within any of the named items
+func (vd *VectorData) pathsWithin(paths []*map_locationType, within []string,
+		) ([]*map_locationType, error) {
+	containers := map[string]bool{}
+	for _, name := range within {
+		if _, exists := vd.mapItems[name]; !exists {
+			return nil, fmt.Errorf("unknown map item '%s'", name)
+		}
+		containers[name] = true
+	}
+	var found []*map_locationType
+	for _, path := range paths {
+		visited := map[string]bool{}
+		toVisit := []string{path.Name()}
+		for len(toVisit) > 0 {
+			name := toVisit[len(toVisit) - 1]
+			toVisit = toVisit[:len(toVisit) - 1]
+			if containers[name] {
+				found = append(found, path)
+				break
+			}
+			if visited[name] {
+				continue
+			}
+			visited[name] = true
+			if item, exists := vd.mapItems[name]; exists {
+				toVisit = append(toVisit, item.Referrers()...)
+			}
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no paths lie within %s", strings.Join(within, ", "))
+	}
+	return found, nil
+}
+
+
+// Named paths are referenced by name.  Anonymous paths, second uses of a path, and pieces
+// ending between the vertices of a path are written out as the points of the piece used.
+func formatFoundRoute(routeName, from, to string, pieces []routePiece, meters float64) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "; shortest route from %s to %s: %.1f meters (%.2f miles)\n", from, to,
+		meters, meters / great.METERS_PER_MILE)
+	fmt.Fprintf(&sb, "(route %s\n\t(segment\n", routeName)
+	references := []string{from}
+	flushReferences := func () {
+		if len(references) > 0 {
+			fmt.Fprintf(&sb, "\t\t(paths %s)\n", strings.Join(references, " "))
+			references = nil
+		}
+	}
+	used := map[*map_locationType]bool{}
+	for _, piece := range pieces {
+		name := exportableName(piece.path)
+		atVertices := piece.from.fraction == 0 && piece.to.fraction == 0
+		if len(name) > 0 && !used[piece.path] && atVertices {
+			references = append(references, name)
+			used[piece.path] = true
+			continue
+		}
+		flushReferences()
+		first, last := piece.from, piece.to
+		if last.before(first) {
+			first, last = last, first
+		}
+		points := locationPairs(piece.path.pointsBetween(first, last)).latlongPairs(false)
+		sb.WriteString("\t\t(path")
+		for _, point := range points {
+			fmt.Fprintf(&sb, "\n\t\t\t%s %s", point.lat, point.long)
+		}
+		sb.WriteString(")\n")
+	}
+	references = append(references, to)
+	flushReferences()
+	sb.WriteString("\t)\n)\n")
+	return sb.String()
+}
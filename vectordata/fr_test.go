@@ -0,0 +1,101 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"strings"
+	"testing"
+)
+
+
+// A network of paths with an anonymous path in a segment and waypoints at vertices of the
+// paths, between vertices, and off the paths
+const findRoutePaths = `
+	(path ab 30.0 -83.0 30.0 -83.02)
+	(path bc 30.0 -83.02 30.01 -83.01)
+	(path adc 30.0 -83.0 30.01 -83.0 30.01 -83.01)
+	(path xy 30.5 -83.5 30.6 -83.6)
+	(segment sg (path 30.01 -83.01 30.02 -83.01))
+	(point pa 30.0 -83.0)
+	(point pc 30.01 -83.01)
+	(marker pd 30.01 -83.0)
+	(point pe 30.02 -83.01)
+	(marker pm 30.0003 -83.0201)
+	(point pn 30.0 -83.01)
+	(point px 30.6 -83.6)
+	`
+
+
+func Test_findRoute(T *testing.T) {
+	for _, test := range []struct {
+		from, to string
+		within []string
+		references []string
+	}{
+		{"pa", "pc", nil, []string{"(paths pa adc pc)"}},
+		{"pc", "pa", []string{"ab", "bc"}, []string{"(paths pc bc ab pa)"}},
+		{"pd", "pc", nil, []string{"(paths pd adc pc)"}},
+		{"pd", "pe", nil, []string{"(paths pd adc)", "(path\n", "(paths pe)"}},
+		// pn lies between the vertices of ab
+		{"pn", "pc", nil, []string{"(paths pn)", "(path\n\t\t\t30.000000 -83.010000\n" +
+			"\t\t\t30.000000 -83.020000)", "(paths bc pc)"}},
+	} {
+		vd := prepareAndParseStrings(T, `(layers
+			(layer one
+				(menuitem "Look")
+				(features ab bc adc xy sg pa pc pd pe pm pn px)
+			)
+		)` + findRoutePaths)
+		text, meters, err := vd.FindRoute("found", test.from, test.to, test.within)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		for _, reference := range test.references {
+			if !strings.Contains(text, reference) {
+				T.Fatalf("%s to %s: expected %s in\n%s", test.from, test.to, reference, text)
+			}
+		}
+
+		// The found route must thread and measure like a hand-written one
+		vd = prepareAndParseStrings(T, `(layers
+			(layer one
+				(menuitem "Look")
+				(features ab bc adc xy sg pa pc pd pe pm pn px found)
+			)
+		)` + findRoutePaths, text)
+		measured, err := vd.MeasurePath("found")
+		if err != nil {
+			T.Fatalf("%s to %s: %s\n%s", test.from, test.to, err, text)
+		}
+		compareTestLengths(T, test.from + "-" + test.to, measured, meters)
+	}
+}
+
+
+func Test_findRouteErrors(T *testing.T) {
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features ab bc adc xy sg pa pc pd pe pm pn px)
+		)
+	)` + findRoutePaths)
+	for _, test := range []struct {
+		from, to string
+		within []string
+		expected string
+	}{
+		{"pa", "px", nil, "no paths connect 'pa' and 'px'"},
+		{"pa", "pm", nil, "marker 'pm' is not on any path"},
+		{"pa", "pa", nil, "'pa' and 'pa' are at the same place"},
+		{"pa", "pc", []string{"nothing"}, "unknown map item 'nothing'"},
+		{"pa", "pc", []string{"pe"}, "no paths lie within pe"},
+		{"pa", "pe", []string{"ab", "adc"}, "point 'pe' is not on any path"},
+	} {
+		_, _, err := vd.FindRoute("found", test.from, test.to, test.within)
+		if err == nil || err.Error() != test.expected {
+			T.Fatalf("%s to %s: expected error '%s', got %v", test.from, test.to,
+				test.expected, err)
+		}
+	}
+}
@@ -6,7 +6,10 @@ package vectordata
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
+
+	"potano.misiones/great"
 )
 
 
@@ -158,7 +161,7 @@ func Test_generateBasic(T *testing.T) {
 	vd := prepareAndParseStrings(T, sourceText)
 
 
-	generated, err := vd.generateJson()
+	generated, err := vd.generateJson(FixedPointEncoding, nil)
 	if err != nil {
 		T.Fatal(err.Error())
 	}
@@ -330,7 +333,7 @@ func Test_generateConcurrentSegment(T *testing.T) {
 	`
 	vd := prepareAndParseStrings(T, sourceText)
 
-	generated, err := vd.generateJson()
+	generated, err := vd.generateJson(FixedPointEncoding, nil)
 	if err != nil {
 		T.Fatal(err.Error())
 	}
@@ -491,3 +494,344 @@ func Test_generateConcurrentSegment(T *testing.T) {
 			30.351842,-83.520299,30.342397,-83.509359,
 		})
 }
+
+
+func Test_generateOutputWrappers(T *testing.T) {
+	sourceText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features mark1)
+		)
+	)
+	(marker mark1
+		(popup "Bell \x07 ☀")
+		30.351842 -83.520299
+	)
+	(config
+		(baseStyle baseStyle "color=#1f78b4")
+	)
+	`
+	vd := prepareAndParseStrings(T, strings.Replace(sourceText, "\\x07", "\x07", 1))
+	blob, err := vd.GenerateOutput(OutputOptions{Wrapper: JsonOutput})
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	var doc map[string]any
+	err = json.Unmarshal([]byte(blob), &doc)
+	if err != nil {
+		T.Fatalf("output is not strict JSON: %s", err)
+	}
+	checkAnyValue(T, doc["texts"], "texts", []any{0, "Bell \x07 ☀"})
+
+	for _, tc := range []struct {
+		opts OutputOptions
+		prefix, suffix string
+	}{
+		{OutputOptions{}, "allData={", "}"},
+		{OutputOptions{Wrapper: GlobalOutput, GlobalName: "window.mapData"},
+			"window.mapData={", "}"},
+		{OutputOptions{Wrapper: ModuleOutput}, "export default {", "};"},
+	} {
+		got, err := vd.GenerateOutput(tc.opts)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		if !strings.HasPrefix(got, tc.prefix) || !strings.HasSuffix(got, tc.suffix) {
+			T.Fatalf("expected output wrapped in %s ... %s, got %s", tc.prefix, tc.suffix,
+				got)
+		}
+		unwrapped := got[len(tc.prefix) - 1:len(got) - len(tc.suffix) + 1]
+		if unwrapped != blob {
+			T.Fatalf("wrapped output differs from JSON output")
+		}
+	}
+
+	_, err = vd.GenerateOutput(OutputOptions{Wrapper: GlobalOutput, GlobalName: "9lives"})
+	if err == nil || err.Error() != "'9lives' is not a valid Javascript variable name" {
+		T.Fatalf("expected invalid-name error, got %v", err)
+	}
+}
+
+
+func Test_generateLayerFiles(T *testing.T) {
+	sourceText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features mark1 path1)
+		)
+		(layer two
+			(menuitem "Over here")
+			(features path1)
+		)
+	)
+	(marker mark1
+		(popup "Here")
+		30.351842 -83.520299
+	)
+	(path path1
+		(popup "Trail")
+		30.350075 -83.507595
+		30.350177 -83.507918
+	)
+	(config
+		(baseStyle baseStyle "color=#1f78b4")
+	)
+	`
+	vd := prepareAndParseStrings(T, sourceText)
+	files, err := vd.GenerateLayerFiles("out/data.json", OutputOptions{Wrapper: JsonOutput})
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if len(files) != 3 {
+		T.Fatalf("expected 3 files, got %d", len(files))
+	}
+	docs := make([]any, len(files))
+	for i, want := range []string{"out/data.json", "out/data_one.json", "out/data_two.json"} {
+		if files[i].FileName != want {
+			T.Fatalf("file %d: expected name %s, got %s", i, want, files[i].FileName)
+		}
+		err = json.Unmarshal([]byte(files[i].Blob), &docs[i])
+		if err != nil {
+			T.Fatalf("file %s: %s", want, err)
+		}
+	}
+	checkAnyValue(T, docs[0], "index", map[string]any{
+		"styles": []any{0},
+		"menuitems": []any{
+			map[string]any{"menuitem": "Look", "file": "data_one.json", "f": []any{0, 1}},
+			map[string]any{"menuitem": "Over here", "file": "data_two.json",
+				"f": []any{0}},
+		},
+		"texts": []any{0, "Here", "Trail"},
+	})
+	checkAnyValue(T, docs[1], "layer one", map[string]any{
+		"features": []any{
+			map[string]any{"t": "marker", "popup": 1, "loc": []any{0, 2}},
+			map[string]any{"t": "path", "popup": 2, "loc": []any{2, 4}},
+		},
+		"points": []any{30.351842, -83.520299, 30.350075, -83.507595, 30.350177,
+			-83.507918},
+	})
+	checkAnyValue(T, docs[2], "layer two", map[string]any{
+		"features": []any{
+			map[string]any{"t": "path", "popup": 2, "loc": []any{0, 4}},
+		},
+		"points": []any{30.350075, -83.507595, 30.350177, -83.507918},
+	})
+
+	files, err = vd.GenerateLayerFiles("data.js", OutputOptions{})
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if !strings.HasPrefix(files[1].Blob, "allData_one={") {
+		T.Fatalf("unexpected layer-file wrapper: %s", files[1].Blob)
+	}
+}
+
+
+func Test_polylineValues(T *testing.T) {
+	// Example from Google's description of the algorithm, at a precision of five digits
+	var buf []byte
+	var prev [2]int64
+	for i, v := range []int64{3850000, -12020000, 4070000, -12095000, 4325200, -12645300} {
+		buf = appendPolylineValue(buf, v - prev[i & 1])
+		prev[i & 1] = v
+	}
+	if string(buf) != "_p~iF~ps|U_ulLnnqC_mqNvxq`@" {
+		T.Fatalf("unexpected polyline encoding %s", buf)
+	}
+}
+
+
+func decodeTestPoints(T *testing.T, doc map[string]any) []any {
+	T.Helper()
+	var deltas []int64
+	switch doc["pointsEncoding"] {
+	case "delta":
+		for _, v := range doc["points"].([]any) {
+			deltas = append(deltas, int64(v.(float64)))
+		}
+	case "polyline6":
+		str := doc["points"].(string)
+		for pos := 0; pos < len(str); {
+			var v int64
+			for shift := 0; ; shift += 5 {
+				chunk := int64(str[pos]) - 63
+				pos++
+				v |= (chunk & 0x1f) << shift
+				if chunk < 0x20 {
+					break
+				}
+			}
+			if v & 1 > 0 {
+				v = ^v
+			}
+			deltas = append(deltas, v >> 1)
+		}
+	default:
+		T.Fatalf("unexpected points encoding %v", doc["pointsEncoding"])
+	}
+	out := make([]any, len(deltas))
+	var prev [2]int64
+	for i, delta := range deltas {
+		prev[i & 1] += delta
+		out[i] = float64(prev[i & 1]) / 1e6
+	}
+	return out
+}
+
+
+func Test_generateEncodedPoints(T *testing.T) {
+	sourceText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features mark1 path1)
+		)
+	)
+	(marker mark1
+		30.351842 -83.520299
+	)
+	(path path1
+		30.350075 -83.507595
+		30.350177 -83.507918
+		-0.000001 0.000010
+	)
+	(config
+		(baseStyle baseStyle "color=#1f78b4")
+	)
+	`
+	vd := prepareAndParseStrings(T, sourceText)
+	for _, encoding := range []int{DeltaEncoding, Polyline6Encoding} {
+		blob, err := vd.GenerateOutput(OutputOptions{Wrapper: JsonOutput,
+			PointsEncoding: encoding})
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		var doc map[string]any
+		err = json.Unmarshal([]byte(blob), &doc)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		checkAnyValue(T, decodeTestPoints(T, doc), fmt.Sprintf("points %d", encoding),
+			[]any{30.351842, -83.520299, 30.350075, -83.507595, 30.350177, -83.507918,
+				-0.000001, 0.00001})
+	}
+	_, err := vd.GenerateOutput(OutputOptions{PointsEncoding: 7})
+	if err == nil || err.Error() != "unknown points encoding 7" {
+		T.Fatalf("expected unknown-encoding error, got %v", err)
+	}
+}
+
+
+const simplifyTestSource = `(layers
+	(layer one
+		(menuitem "Look")
+		(features theRoad hill)
+	)
+)
+(route theRoad
+	(segment
+		(paths wp0 pathA wp pathB)
+	)
+)
+(path pathA
+	30.000000 -83.500000
+	30.000010 -83.490000
+	30.000000 -83.480000
+	30.000010 -83.470000
+	30.000000 -83.460000
+)
+(point wp0 30.000000 -83.500000)
+(point wp 30.000000 -83.480000)
+(path pathB
+	30.000000 -83.480000
+	30.005000 -83.480000
+	30.010000 -83.480000
+)
+(polygon hill
+	29.50 -83.43  29.50 -83.42  29.50 -83.41
+	29.40 -83.41  29.40 -83.43
+)
+(config
+	(baseStyle baseStyle "color=#1f78b4")
+)
+`
+
+
+func Test_generateSimplified(T *testing.T) {
+	vd := prepareAndParseStrings(T, simplifyTestSource)
+	blob, err := vd.GenerateOutput(OutputOptions{Wrapper: JsonOutput,
+		Tolerances: []float64{5, 2000}})
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	var doc map[string]any
+	err = json.Unmarshal([]byte(blob), &doc)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	checkAnyValue(T, doc["tolerances"], "tolerances", []any{5, 2000})
+	checkAnyValue(T, doc["features"], "features", []any{
+		map[string]any{"t": "route", "f": []any{1}},
+		map[string]any{"t": "segment", "f": []any{2, 3}},
+		// The part of pathA up to waypoint wp
+		map[string]any{"t": "path", "loc": []any{0, 6}, "simp": []any{
+			[]any{10, 4}, []any{16, 4}}},
+		map[string]any{"t": "path", "loc": []any{22, 6}, "simp": []any{
+			[]any{28, 4}, []any{32, 4}}},
+		map[string]any{"t": "polygon", "loc": []any{36, 10}, "simp": []any{
+			[]any{46, 8}, []any{54, 6}}},
+	})
+	checkAnyValue(T, doc["points"], "points", []any{
+		// pathA full resolution
+		30.0, -83.5, 30.00001, -83.49, 30.0, -83.48, 30.00001, -83.47, 30.0, -83.46,
+		// pathA simplified; the vertex at waypoint wp stays
+		30.0, -83.5, 30.0, -83.48, 30.0, -83.46,
+		30.0, -83.5, 30.0, -83.48, 30.0, -83.46,
+		// pathB
+		30.0, -83.48, 30.005, -83.48, 30.01, -83.48,
+		30.0, -83.48, 30.01, -83.48,
+		30.0, -83.48, 30.01, -83.48,
+		// hill
+		29.5, -83.43, 29.5, -83.42, 29.5, -83.41, 29.4, -83.41, 29.4, -83.43,
+		29.5, -83.43, 29.5, -83.41, 29.4, -83.41, 29.4, -83.43,
+		29.5, -83.43, 29.4, -83.41, 29.4, -83.43,
+	})
+
+	// Measurement still uses the full-resolution path
+	meters, err := vd.MeasurePath("pathA")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	full := great.MetersInPath(vd.mapItems["pathA"].(*map_locationType).location.asFloatSlice())
+	compareTestLengths(T, "pathA", full, meters)
+}
+
+
+func Test_generatePixelCircle(T *testing.T) {
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features dot ring)
+		)
+	)
+	(circle dot 30.0 -83.0 (pixels 4))
+	(circle ring 30.0 -83.1 (radius 300))
+	(config
+		(baseStyle baseStyle "color=#1f78b4")
+	)
+	`)
+	blob, err := vd.GenerateOutput(OutputOptions{Wrapper: JsonOutput})
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	var doc map[string]any
+	err = json.Unmarshal([]byte(blob), &doc)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	checkAnyValue(T, doc["features"], "features", []any{
+		map[string]any{"t": "circle", "asPixels": true, "radius": 4, "loc": []any{0, 2}},
+		map[string]any{"t": "circle", "asPixels": false, "radius": 300, "loc": []any{2, 2}},
+	})
+}
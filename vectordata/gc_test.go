@@ -0,0 +1,171 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+
+// Path v crosses path h between vertices; path t ends on h between vertices
+const crossingNetwork = `(layers
+		(layer one
+			(menuitem "Look")
+			(features road)
+		)
+	)
+	(route road
+		(segment
+			(paths t h v pe)
+		)
+	)
+	(path h 30.0 -83.0 30.0 -83.02)
+	(path v 29.99 -83.01 30.01 -83.01)
+	(path t 29.995 -83.005 30.0 -83.005)
+	(point pe 30.01 -83.01)
+	`
+
+
+func Test_geometricCrossingReport(T *testing.T) {
+	vd := prepareAndParseStringsIgnoreThreadingError(T, crossingNetwork)
+	if len(vd.DeferredErrors()) == 0 {
+		T.Fatalf("expected threading to fail without inserted vertices")
+	}
+	if len(vd.GeometricCrossings()) != 0 {
+		T.Fatalf("expected no crossings without a crossing check")
+	}
+
+	vd = prepareAndParseStringsIgnoreThreadingError(T, crossingNetwork,
+		"(config (crossingCheck report))")
+	crossings := vd.GeometricCrossings()
+	expected := []string{
+		"path 'h' between points 1 and 2 crosses path 'v' between points 1 and 2 " +
+			"at (30.000000,-83.010000)",
+		"path 'h' between points 1 and 2 crosses path 't' at point 2 " +
+			"at (30.000000,-83.005000)",
+	}
+	if len(crossings) != len(expected) {
+		T.Fatalf("expected %d crossings, got %d", len(expected), len(crossings))
+	}
+	for i, crossing := range crossings {
+		if crossing.String() != expected[i] {
+			T.Fatalf("expected '%s', got '%s'", expected[i], crossing.String())
+		}
+	}
+	if len(vd.mapItems["h"].(*map_locationType).location) != 4 {
+		T.Fatalf("expected report not to change path h")
+	}
+}
+
+
+func Test_geometricCrossingInsert(T *testing.T) {
+	vd := prepareAndParseStrings(T, crossingNetwork, "(config (crossingCheck insert))")
+	crossings := vd.GeometricCrossings()
+	if len(crossings) != 2 || !crossings[0].Inserted {
+		T.Fatalf("expected 2 inserted crossings, got %v", crossings)
+	}
+	for _, test := range []struct {
+		name, expected string
+	}{
+		{"h", "30.000000 -83.000000 30.000000 -83.005000 30.000000 -83.010000 " +
+			"30.000000 -83.020000"},
+		{"v", "29.990000 -83.010000 30.000000 -83.010000 30.010000 -83.010000"},
+		{"t", "29.995000 -83.005000 30.000000 -83.005000"},
+	} {
+		var got []string
+		for _, angle := range vd.mapItems[test.name].(*map_locationType).location {
+			got = append(got, angle.String())
+		}
+		if strings.Join(got, " ") != test.expected {
+			T.Fatalf("path %s: expected %s, got %s", test.name, test.expected,
+				strings.Join(got, " "))
+		}
+	}
+	meters, err := vd.MeasurePath("road")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "road", meters, vd.earth.MetersInPath([]float64{
+		29.995, -83.005, 30.0, -83.005, 30.0, -83.01, 30.01, -83.01}))
+}
+
+
+func Test_geometricCrossingFinder(T *testing.T) {
+	edge := func (locationIndex locationIndexType, lat1, long1, lat2, long2 locAngleType,
+			) gridEdge {
+		return gridEdge{locationIndex, 0, latlongType{lat1, long1}, latlongType{lat2, long2}}
+	}
+	for _, test := range []struct {
+		a, b gridEdge
+		found bool
+		point latlongType
+	}{
+		{edge(1, 0, 0, 10, 10), edge(2, 0, 10, 10, 0), true, latlongType{5, 5}},
+		{edge(1, 0, 0, 10, 10), edge(2, 0, 10, 3, 7), false, latlongType{}},
+		{edge(1, 0, 0, 10, 10), edge(2, 10, 10, 20, 0), false, latlongType{}},
+		{edge(1, 0, 0, 10, 10), edge(2, 5, 5, 20, 0), true, latlongType{5, 5}},
+		{edge(1, 0, 0, 10, 10), edge(2, 2, 2, 20, 20), false, latlongType{}},
+		{edge(1, 0, 0, 0, 9), edge(2, -1, 3, 2, 3), true, latlongType{0, 3}},
+	} {
+		crossing, found := edgeCrossing(test.a, test.b)
+		if found != test.found || (found && crossing.point != test.point) {
+			T.Fatalf("%v and %v: expected %v %v, got %v %v", test.a, test.b, test.found,
+				test.point, found, crossing.point)
+		}
+	}
+}
+
+
+func Test_geometricCrossingAtVertex(T *testing.T) {
+	// The middle vertex of v lies on the edge of h
+	vd := prepareAndParseStringsIgnoreThreadingError(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features h v)
+		)
+	)
+	(path h 30.0 -83.0 30.0 -83.02)
+	(path v 29.99 -83.01 30.0 -83.01 30.01 -83.01)
+	`, "(config (crossingCheck report))")
+	crossings := vd.GeometricCrossings()
+	expected := "path 'h' between points 1 and 2 crosses path 'v' at point 2 " +
+		"at (30.000000,-83.010000)"
+	if len(crossings) != 1 || crossings[0].String() != expected {
+		T.Fatalf("expected only '%s', got %v", expected, crossings)
+	}
+}
+
+
+func Test_geometricCrossingLongEdge(T *testing.T) {
+	// Short edges along a line of latitude, crossed by one long diagonal edge
+	short := locationPairs{}
+	for i := 0; i <= 2000; i++ {
+		short = append(short, 30000000, locAngleType(-83000000 + 50 * i))
+	}
+	locations := map[locationIndexType]locationPairs{
+		1: short,
+		2: {29500000, -83499975, 30500000, -82499975},
+	}
+	crossings := findGeometricCrossings(locations)
+	if len(crossings) != 1 || crossings[0].point != (latlongType{30000000, -82999975}) {
+		T.Fatalf("expected one crossing at (30,-82.999975), got %v", crossings)
+	}
+	// The long edge occupies cells along its course, not all those of its bounding box
+	long := gridEdge{2, 0, latlongType{0, 0}, latlongType{1000000, 1000000}}
+	if cells := long.cells(1000); len(cells) > 4 * 1001 {
+		T.Fatalf("expected about 3000 cells, got %d", len(cells))
+	}
+}
+
+
+func Test_crossingCheckErrors(T *testing.T) {
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(
+		"(config (crossingCheck fix))")},
+		"infile0:1: unknown crossing check 'fix'; expected report or insert")
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(
+		"(config (crossingCheck report) (crossingCheck insert))")},
+		"infile0:1: crossing check already set")
+}
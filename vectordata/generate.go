@@ -4,22 +4,158 @@
 package vectordata
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"strconv"
 )
 
 
+// Wrappers around the generated data
+const (
+	GlobalOutput = iota		// assignment to a global variable
+	JsonOutput			// bare JSON, as for fetch()
+	ModuleOutput			// default export of an ES module
+)
+
+const defaultGlobalName = "allData"
+
+type OutputOptions struct {
+	Wrapper int
+	GlobalName string		// variable assigned by GlobalOutput; default is allData
+	PointsEncoding int
+	Tolerances []float64		// in meters, for simplified versions of paths and polygons
+}
+
+
 func (vd *VectorData) GenerateJs() (string, error) {
-	json, err := vd.generateJson()
-	return "allData=" + json, err
+	return vd.GenerateOutput(OutputOptions{})
+}
+
+func (vd *VectorData) GenerateOutput(opts OutputOptions) (string, error) {
+	blob, err := vd.generateJson(opts.PointsEncoding, opts.Tolerances)
+	if err != nil {
+		return "", err
+	}
+	return wrapOutput(blob, opts)
+}
+
+func wrapOutput(blob string, opts OutputOptions) (string, error) {
+	switch opts.Wrapper {
+	case GlobalOutput:
+		name := opts.GlobalName
+		if len(name) == 0 {
+			name = defaultGlobalName
+		} else if !globalNameRegex.MatchString(name) {
+			return "", fmt.Errorf("'%s' is not a valid Javascript variable name", name)
+		}
+		return name + "=" + blob, nil
+	case JsonOutput:
+		return blob, nil
+	case ModuleOutput:
+		return "export default " + blob + ";", nil
+	}
+	return "", fmt.Errorf("unknown output wrapper %d", opts.Wrapper)
+}
+
+var globalNameRegex *regexp.Regexp = regexp.MustCompile(
+	"^[A-Za-z_$][A-Za-z0-9_$]*(?:\\.[A-Za-z_$][A-Za-z0-9_$]*)*$")
+
+
+func (vd *VectorData) generateJson(pointsEncoding int, tolerances []float64) (string, error) {
+	jsg, err := vd.newJsGenerator(pointsEncoding, tolerances)
+	if err != nil {
+		return "", err
+	}
+	err = jsg.serializeFromRoot()
+	if err != nil {
+		return "", err
+	}
+	blobs := []string{jsg.styles.json(), jsg.menuitems.json(), jsg.texts.json(),
+		jsg.features.json(), jsg.points.json(jsg.pointsEncoding)}
+	if len(tolerances) > 0 {
+		blobs = append(blobs, jsg.tolerancesJson())
+	}
+	return "{" + strings.Join(blobs, ",") + "}", nil
+}
+
+
+type GeneratedFile struct {
+	FileName string
+	Blob string
+}
+
+// Generates an index file holding the styles, menuitems, and texts plus one file for each
+// layer holding the layer's features and points, so that a layer's data need be fetched
+// only when the layer is switched on.  Layer files are named by inserting an underscore and
+// the layer name before the extension of the index-file name; the "file" member of each
+// menuitem gives the base name of the layer file.  Feature indices in menuitems refer to the
+// features array of the layer file.  In global-variable form, each layer file assigns to a
+// variable named by appending an underscore and the layer name to the index variable's name.
+func (vd *VectorData) GenerateLayerFiles(indexName string, opts OutputOptions) (
+		[]GeneratedFile, error) {
+	jsg, err := vd.newJsGenerator(opts.PointsEncoding, opts.Tolerances)
+	if err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(indexName)
+	stem := strings.TrimSuffix(indexName, ext)
+	globalName := opts.GlobalName
+	if len(globalName) == 0 {
+		globalName = defaultGlobalName
+	}
+	files := []GeneratedFile{{FileName: indexName}}
+	for _, layer := range vd.layersRoot.layers {
+		layerGen := jsg
+		layerGen.features = newGenGroup("features", len(vd.mapItems))
+		layerGen.points = newPointsGroup("points", len(vd.mapItems))
+		features, err := layerGen.resolveFeatures(layer.features)
+		if err != nil {
+			return nil, err
+		}
+		fileName := stem + "_" + layer.Name() + ext
+		jsg.menuitems.addEntry(generateJsObject("menuitem", layer.menuitem,
+			"file", filepath.Base(fileName), "f", features))
+		layerOpts := opts
+		layerOpts.GlobalName = globalName + "_" + layer.Name()
+		blob, err := wrapOutput("{" + layerGen.features.json() + "," +
+			layerGen.points.json(jsg.pointsEncoding) + "}", layerOpts)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, GeneratedFile{fileName, blob})
+	}
+	blobs := []string{jsg.styles.json(), jsg.menuitems.json(), jsg.texts.json()}
+	if len(opts.Tolerances) > 0 {
+		blobs = append(blobs, jsg.tolerancesJson())
+	}
+	files[0].Blob, err = wrapOutput("{" + strings.Join(blobs, ",") + "}", opts)
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
 }
 
-func (vd *VectorData) generateJson() (string, error) {
+
+func (vd *VectorData) newJsGenerator(pointsEncoding int, tolerances []float64) (jsGenerator,
+		error) {
+	if pointsEncoding < 0 || pointsEncoding >= len(pointsEncodingNames) {
+		return jsGenerator{}, fmt.Errorf("unknown points encoding %d", pointsEncoding)
+	}
+	for _, tolerance := range tolerances {
+		if tolerance <= 0 {
+			return jsGenerator{}, fmt.Errorf("simplification tolerance %g is not positive",
+				tolerance)
+		}
+	}
 	if !vd.styler.styleCheckRun() {
 		err := vd.CheckInStylesAndAttestations()
 		if err != nil {
-			return "", err
+			return jsGenerator{}, err
 		}
 	}
 	jsg := jsGenerator{
@@ -29,19 +165,16 @@ func (vd *VectorData) generateJson() (string, error) {
 		texts: newGenGroup("texts", 30),
 		features: newGenGroup("features", len(vd.mapItems)),
 		points: newPointsGroup("points", len(vd.mapItems)),
+		pointsEncoding: pointsEncoding,
+		tolerances: tolerances,
+		simplifications: map[string][]int{},
 	}
 	if vd.styler != nil {
 		jsg.styles.addEntry("0")
 		vd.styler.serializeStyles(jsg)
 	}
 	jsg.texts.addEntry("0")
-	err := jsg.serializeFromRoot()
-	if err != nil {
-		return "", err
-	}
-	blobs := []string{jsg.styles.json(), jsg.menuitems.json(), jsg.texts.json(),
-		jsg.features.json(), jsg.points.json()}
-	return "{" + strings.Join(blobs, ",") + "}", nil
+	return jsg, nil
 }
 
 
@@ -50,6 +183,9 @@ type jsGenerator struct {
 	vd *VectorData
 	styles, menuitems, texts, features *genGroup
 	points *pointsGroup
+	pointsEncoding int
+	tolerances []float64
+	simplifications map[string][]int	// kept vertices by location name and tolerance
 }
 
 type genGroup struct {
@@ -91,7 +227,7 @@ func (gg *genGroup) addStringWithKey(key, text string) int {
 		return index
 	}
 	index = len(gg.blobs)
-	gg.blobs = append(gg.blobs, strconv.Quote(text))
+	gg.blobs = append(gg.blobs, jsonQuote(text))
 	gg.indices[key] = index
 	return index
 }
@@ -113,24 +249,87 @@ func (gg *genGroup) json() string {
 }
 
 
-type pointsGroup = genGroup
+// Encodings of the points array
+const (
+	FixedPointEncoding = iota	// six-decimal floats
+	DeltaEncoding			// integer differences from the previous latitude or longitude
+	Polyline6Encoding		// Google encoded-polyline string at a precision of six digits
+)
+
+var pointsEncodingNames []string = []string{"", "delta", "polyline6"}
+
+type pointsGroup struct {
+	key string
+	values locationPairs
+	indices map[string]int
+}
 
 func newPointsGroup(key string, initSize int) *pointsGroup {
 	initSize *= 2 * 20
-	return &pointsGroup{key, make([]string, 0, initSize), make(map[string]int, initSize)}
+	return &pointsGroup{key, make(locationPairs, 0, initSize), make(map[string]int, initSize)}
+}
+
+func (pg *pointsGroup) index(key string) (int, bool) {
+	i, b := pg.indices[key]
+	return i, b
 }
 
 func (pg *pointsGroup) addPoints(name string, pairs locationPairs) int {
-	index := len(pg.blobs)
-	slice := make([]string, len(pairs))
-	for i, v := range pairs {
-		slice[i] = v.String()
-	}
-	pg.blobs = append(pg.blobs, slice...)
+	index := len(pg.values)
+	pg.values = append(pg.values, pairs...)
 	pg.indices[name] = index
 	return index
 }
 
+// Offsets into the points array count latitudes and longitudes separately no matter what the
+// encoding, so that the decoded array is always that of the fixed-point encoding.
+func (pg *pointsGroup) json(encoding int) string {
+	var blob string
+	switch encoding {
+	case DeltaEncoding:
+		items := make([]string, len(pg.values))
+		var prev [2]locAngleType
+		for i, v := range pg.values {
+			items[i] = strconv.Itoa(int(v - prev[i & 1]))
+			prev[i & 1] = v
+		}
+		blob = "[" + strings.Join(items, ",") + "]"
+	case Polyline6Encoding:
+		var buf []byte
+		var prev [2]locAngleType
+		for i, v := range pg.values {
+			buf = appendPolylineValue(buf, int64(v - prev[i & 1]))
+			prev[i & 1] = v
+		}
+		blob = jsonQuote(string(buf))
+	default:
+		items := make([]string, len(pg.values))
+		for i, v := range pg.values {
+			items[i] = v.String()
+		}
+		blob = "[" + strings.Join(items, ",") + "]"
+	}
+	if encoding != FixedPointEncoding {
+		blob += ",\"pointsEncoding\":" + jsonQuote(pointsEncodingNames[encoding])
+	}
+	return "\"" + pg.key + "\":" + blob
+}
+
+// Appends a value in the form of Google's encoded-polyline algorithm:  the value, shifted left
+// one bit and inverted if negative, is written in five-bit chunks from least significant up,
+// each chunk but the last being ORed with 0x20, and 63 is added to each chunk.
+func appendPolylineValue(buf []byte, value int64) []byte {
+	v := value << 1
+	if value < 0 {
+		v = ^v
+	}
+	for v >= 0x20 {
+		buf = append(buf, byte((0x20 | (v & 0x1f)) + 63))
+		v >>= 5
+	}
+	return append(buf, byte(v + 63))
+}
+
 
 
 
@@ -151,6 +350,9 @@ func (jsg jsGenerator) serializeFromRoot() error {
 func (jsg jsGenerator) resolveFeatures(list []mapItemType) ([]int, error) {
 	resolved := make([]int, 0, len(list))
 	for _, child := range list {
+		if !jsg.vd.isActive(child) {
+			continue
+		}
 		if ref, is := child.(*map_referenceAggregateType); is {
 			group, err := jsg.resolveFeatures(ref.targets)
 			if err != nil {
@@ -178,14 +380,27 @@ func (jsg jsGenerator) serializeMapItem(item mapItemType) (string, error) {
 	t := item.ItemTypeString()
 	var popup nonZeroInt
 	var features []mapItemType
+	var alternative []any
 	style := nonZeroInt(jsg.vd.styler.styleIndex(item))
+	from, to := periodOf(item).fromAndTo()
 	switch item := item.(type) {
 	case *mapFeatureType:
 		popup = item.popup.textIndex(jsg)
 		features = item.features
 	case *mapRouteOrSegmentType:
 		popup = item.popup.textIndex(jsg)
-		features = item.children
+		features = item.componentsWithAlternatives()
+		if ar := item.alternative; ar != nil {
+			alternative = []any{
+				"alt", ar.name(),
+				"altGroup", ar.group.number,
+				"altDefault", ar.isDefault(),
+			}
+		}
+		if item.mileposts != nil {
+			features = append(features[:len(features):len(features)],
+				item.mileposts.featureList()...)
+		}
 	case *map_locationType:
 		popup = item.popup.textIndex(jsg)
 		protoLocation := item.prototypePath
@@ -203,16 +418,25 @@ func (jsg jsGenerator) serializeMapItem(item mapItemType) (string, error) {
 				"t", t,
 				"popup", popup,
 				"style", style,
+				"from", from,
+				"to", to,
 				"asPixels", asPixels,
 				"radius", item.radius,
 				"loc", []int{bigOffset, 2}), nil
 		}
+		var simplified [][]int
+		if item.itemType == mitPath || item.itemType == mitPolygon {
+			simplified = jsg.simplifiedRanges(protoLocation, item)
+		}
 		return generateJsObject(
 			"t", t,
 			"popup", popup,
 			"style", style,
+			"from", from,
+			"to", to,
 			"html", nonEmptyString(item.html),
 			"loc", []int{bigOffset + int(item.offsetInPrototype), len(item.location)},
+			"simp", simplified,
 		), nil
 	default:
 		return "", fmt.Errorf("unhandled item type %s", t)
@@ -221,12 +445,64 @@ func (jsg jsGenerator) serializeMapItem(item mapItemType) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return generateJsObject(
+	return generateJsObject(append([]any{
 		"t", t,
 		"popup", popup,
 		"style", style,
+		"from", from,
+		"to", to,
 		"f", indices,
-	), nil
+	}, alternative...)...), nil
+}
+
+
+// Returns for each tolerance the offset and length in the points array of the simplified
+// version of the item.  Simplified versions are computed for whole prototype paths; since
+// subpaths begin and end at crosspoints, which simplification never removes, each subpath is
+// a contiguous run of its prototype's simplified vertices.
+func (jsg jsGenerator) simplifiedRanges(proto, item *map_locationType) [][]int {
+	ranges := make([][]int, len(jsg.tolerances))
+	first := int(item.offsetInPrototype) >> 1
+	last := first + (len(item.location) >> 1) - 1
+	for i, tolerance := range jsg.tolerances {
+		key := fmt.Sprintf("%s@%d", proto.Name(), i)
+		kept, exists := jsg.simplifications[key]
+		if !exists {
+			kept = simplifiedVertices(jsg.vd.earth, proto, tolerance)
+			jsg.simplifications[key] = kept
+		}
+		start, end := sort.SearchInts(kept, first), sort.SearchInts(kept, last)
+		if end >= len(kept) || kept[start] != first || kept[end] != last {
+			// Fall back to simplifying the subpath by itself
+			key = fmt.Sprintf("%s@%d", item.Name(), i)
+			kept, exists = jsg.simplifications[key]
+			if !exists {
+				kept = simplifiedVertices(jsg.vd.earth, item, tolerance)
+				jsg.simplifications[key] = kept
+			}
+			proto, first, start, end = item, 0, 0, len(kept) - 1
+		}
+		bigOffset, exists := jsg.points.index(key)
+		if !exists {
+			pairs := make(locationPairs, 0, len(kept) << 1)
+			for _, vertex := range kept {
+				pairs = append(pairs, proto.location[vertex << 1],
+					proto.location[(vertex << 1) + 1])
+			}
+			bigOffset = jsg.points.addPoints(key, pairs)
+		}
+		ranges[i] = []int{bigOffset + (start << 1), (end - start + 1) << 1}
+	}
+	return ranges
+}
+
+
+func (jsg jsGenerator) tolerancesJson() string {
+	items := make([]string, len(jsg.tolerances))
+	for i, tolerance := range jsg.tolerances {
+		items[i] = strconv.FormatFloat(tolerance, 'f', -1, 64)
+	}
+	return "\"tolerances\":[" + strings.Join(items, ",") + "]"
 }
 
 
@@ -258,24 +534,29 @@ func generateJsObject(args ...any) string {
 		case locAngleType:
 			str = v.String()
 		case string:
-			str = strconv.Quote(v)
+			str = jsonQuote(v)
 		case nonEmptyString:
 			if len(v) == 0 {
 				continue
 			}
-			str = strconv.Quote(string(v))
+			str = jsonQuote(string(v))
 		case nonZeroInt:
 			if v == 0 {
 				continue
 			}
 			str = strconv.Itoa(int(v))
 		case []int:
+			if len(v) == 0 {
+				continue
+			}
+			str = generateJsArray(v)
+		case [][]int:
 			if len(v) == 0 {
 				continue
 			}
 			items := make([]string, len(v))
 			for i, val := range v {
-				items[i] = strconv.Itoa(val)
+				items[i] = generateJsArray(val)
 			}
 			str = "[" + strings.Join(items, ",") + "]"
 		case bool:
@@ -283,8 +564,25 @@ func generateJsObject(args ...any) string {
 		default:
 			continue
 		}
-		entries = append(entries, strconv.Quote(key.(string)) + ":" + str)
+		entries = append(entries, jsonQuote(key.(string)) + ":" + str)
 	}
 	return "{" + strings.Join(entries, ",") + "}"
 }
 
+
+// Unlike strconv.Quote, produces only escapes which are legal in JSON
+func jsonQuote(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func generateJsArray(list []int) string {
+	items := make([]string, len(list))
+	for i, val := range list {
+		items[i] = strconv.Itoa(val)
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
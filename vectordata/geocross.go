@@ -0,0 +1,394 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The crossing finder joins paths only at shared vertices.  This optional pass finds the places
+// where edges of different paths cross or touch between vertices so that they may be reported
+// or, on request, joined by inserting a shared vertex into each path.  Edges are bucketed in a
+// grid by the cells they pass through so that only edges in the same cells are compared.
+
+const (
+	noCrossingCheck = iota
+	reportCrossings
+	insertCrossingVertices
+)
+
+var crossingCheckModes = map[string]int{
+	"report": reportCrossings,
+	"insert": insertCrossingVertices,
+}
+
+
+func (vd *VectorData) setCrossingCheck(item *mapCrossingCheckType) error {
+	if vd.crossingCheckSet {
+		return item.Error("crossing check already set")
+	}
+	mode, known := crossingCheckModes[item.mode]
+	if !known {
+		return item.Error("unknown crossing check '%s'; expected report or insert", item.mode)
+	}
+	vd.crossingFinder.crossingCheck = mode
+	vd.crossingCheckSet = true
+	return nil
+}
+
+
+// Overrides the crossing check, if any, given in the config section.  Must be called before
+// ResolveReferences.
+func (vd *VectorData) SetCrossingCheck(mode string) error {
+	checkMode, known := crossingCheckModes[mode]
+	if !known {
+		return fmt.Errorf("unknown crossing check '%s'; expected report or insert", mode)
+	}
+	vd.crossingFinder.crossingCheck = checkMode
+	vd.crossingCheckSet = true
+	return nil
+}
+
+
+
+// One of the two edges at a geometric crossing
+type crossingSide struct {
+	locationIndex locationIndexType
+	offset locationIndexType		// offset of the vertex starting the edge
+	fraction float64			// position of the crossing along the edge
+	needsVertex bool			// whether the crossing lies between vertices
+}
+
+type geometricCrossing struct {
+	point latlongType
+	sides [2]crossingSide
+}
+
+type GeometricCrossing struct {
+	Inserted bool
+	Description string
+}
+
+func (gc GeometricCrossing) String() string {
+	if gc.Inserted {
+		return "inserted vertex where " + gc.Description
+	}
+	return gc.Description
+}
+
+// Lists the places where edges of different paths cross or touch between vertices, ordered by
+// the pair of paths involved
+func (vd *VectorData) GeometricCrossings() []GeometricCrossing {
+	cf := vd.crossingFinder
+	if len(cf.crossings) == 0 {
+		return nil
+	}
+	locations := vd.locationsByIndex()
+	describe := func (side crossingSide) string {
+		where := fmt.Sprintf("between points %d and %d", side.offset / 2 + 1,
+			side.offset / 2 + 2)
+		if !side.needsVertex {
+			if side.fraction > 0.5 {
+				where = fmt.Sprintf("at point %d", side.offset / 2 + 2)
+			} else {
+				where = fmt.Sprintf("at point %d", side.offset / 2 + 1)
+			}
+		}
+		loc := locations[side.locationIndex]
+		if loc == nil {
+			return where
+		}
+		return fmt.Sprintf("%s '%s' %s", loc.ItemTypeString(), loc.Name(), where)
+	}
+	list := make([]GeometricCrossing, len(cf.crossings))
+	for i, crossing := range cf.crossings {
+		list[i] = GeometricCrossing{
+			Inserted: cf.crossingCheck == insertCrossingVertices,
+			Description: fmt.Sprintf("%s crosses %s at (%s,%s)", describe(crossing.sides[0]),
+				describe(crossing.sides[1]), crossing.point.lat, crossing.point.long),
+		}
+	}
+	return list
+}
+
+
+
+// Edge between a vertex and the next in a location
+type gridEdge struct {
+	locationIndex, offset locationIndexType
+	p1, p2 latlongType
+}
+
+type gridCell struct {
+	lat, long int64
+}
+
+// Finds the crossings of edges of different locations.  Locations are given as the
+// concatenations of their records.
+func findGeometricCrossings(locations map[locationIndexType]locationPairs) []geometricCrossing {
+	var edges []gridEdge
+	var extent float64
+	for locationIndex, pairs := range locations {
+		for i := 0; i + 3 < len(pairs); i += 2 {
+			edge := gridEdge{locationIndex, locationIndexType(i), pairs.latlongPair(i),
+				pairs.latlongPair(i + 2)}
+			edges = append(edges, edge)
+			extent += math.Max(math.Abs(float64(edge.p2.lat - edge.p1.lat)),
+				math.Abs(float64(edge.p2.long - edge.p1.long)))
+		}
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	// Cells the size of the average edge hold few edges each
+	cellSize := int64(math.Max(1, extent / float64(len(edges))))
+	grid := map[gridCell][]int{}
+	for i, edge := range edges {
+		for _, cell := range edge.cells(cellSize) {
+			grid[cell] = append(grid[cell], i)
+		}
+	}
+
+	var crossings []geometricCrossing
+	seen := map[[2]int]bool{}
+	for _, list := range grid {
+		for i, e1 := range list {
+			for _, e2 := range list[i+1:] {
+				a, b := edges[e1], edges[e2]
+				if a.locationIndex == b.locationIndex {
+					continue
+				}
+				key := [2]int{e1, e2}
+				if e2 < e1 {
+					key = [2]int{e2, e1}
+				}
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				if crossing, found := edgeCrossing(a, b); found {
+					crossings = append(crossings, crossing)
+				}
+			}
+		}
+	}
+	sort.Slice(crossings, func (i, j int) bool {
+		a, b := crossings[i].sides, crossings[j].sides
+		if a[0].locationIndex != b[0].locationIndex {
+			return a[0].locationIndex < b[0].locationIndex
+		}
+		if a[1].locationIndex != b[1].locationIndex {
+			return a[1].locationIndex < b[1].locationIndex
+		}
+		if a[0].offset != b[0].offset {
+			return a[0].offset < b[0].offset
+		}
+		if a[0].fraction != b[0].fraction {
+			return a[0].fraction < b[0].fraction
+		}
+		return a[1].offset < b[1].offset
+	})
+
+	// A vertex of one path lying on an edge of another touches that edge from the edges on
+	// both sides of the vertex
+	type crossingKey struct {
+		locations [2]locationIndexType
+		point latlongType
+	}
+	found := map[crossingKey]bool{}
+	distinct := crossings[:0]
+	for _, crossing := range crossings {
+		key := crossingKey{[2]locationIndexType{crossing.sides[0].locationIndex,
+			crossing.sides[1].locationIndex}, crossing.point}
+		if !found[key] {
+			found[key] = true
+			distinct = append(distinct, crossing)
+		}
+	}
+	return distinct
+}
+
+
+// Lists the grid cells through which the edge passes, column by column of longitude.  The
+// range of latitudes in each column is widened by a microdegree to allow for rounding.
+func (edge gridEdge) cells(cellSize int64) []gridCell {
+	p1, p2 := edge.p1, edge.p2
+	if p2.long < p1.long {
+		p1, p2 = p2, p1
+	}
+	long1, long2 := int64(p1.long), int64(p2.long)
+	lat1, lat2 := float64(p1.lat), float64(p2.lat)
+	latAt := func (long int64) float64 {
+		if long2 == long1 {
+			return lat1
+		}
+		return lat1 + (lat2 - lat1) * float64(long - long1) / float64(long2 - long1)
+	}
+	var cells []gridCell
+	for column := floorDiv(long1, cellSize); column <= floorDiv(long2, cellSize); column++ {
+		from, to := column * cellSize, (column + 1) * cellSize
+		if from < long1 {
+			from = long1
+		}
+		if to > long2 {
+			to = long2
+		}
+		south, north := latAt(from), latAt(to)
+		if long1 == long2 {
+			south, north = lat1, lat2
+		}
+		if south > north {
+			south, north = north, south
+		}
+		last := floorDiv(int64(math.Ceil(north)) + 1, cellSize)
+		for row := floorDiv(int64(math.Floor(south)) - 1, cellSize); row <= last; row++ {
+			cells = append(cells, gridCell{row, column})
+		}
+	}
+	return cells
+}
+
+
+// Finds where two edges cross or where an end of one touches the other between its vertices.
+// Edges which meet only at a shared vertex, and overlapping collinear edges, do not count.
+func edgeCrossing(a, b gridEdge) (geometricCrossing, bool) {
+	if a.locationIndex > b.locationIndex {
+		a, b = b, a
+	}
+	c3, c4 := turn(b.p1, b.p2, a.p1), turn(b.p1, b.p2, a.p2)
+	o1, o2 := sign(turn(a.p1, a.p2, b.p1)), sign(turn(a.p1, a.p2, b.p2))
+	o3, o4 := sign(c3), sign(c4)
+	crossing := geometricCrossing{sides: [2]crossingSide{
+		{locationIndex: a.locationIndex, offset: a.offset, needsVertex: true},
+		{locationIndex: b.locationIndex, offset: b.offset, needsVertex: true},
+	}}
+	switch {
+	case o1 * o2 < 0 && o3 * o4 < 0:
+		// Proper crossing
+		fraction := float64(c3) / (float64(c3) - float64(c4))
+		lat := float64(a.p1.lat) + fraction * float64(a.p2.lat - a.p1.lat)
+		long := float64(a.p1.long) + fraction * float64(a.p2.long - a.p1.long)
+		crossing.point = latlongType{locAngleType(math.Round(lat)),
+			locAngleType(math.Round(long))}
+	case o1 == 0 && o2 != 0 && o3 * o4 < 0:
+		crossing.point = b.p1
+		crossing.sides[1].needsVertex = false
+	case o2 == 0 && o1 != 0 && o3 * o4 < 0:
+		crossing.point = b.p2
+		crossing.sides[1].needsVertex = false
+		crossing.sides[1].fraction = 1
+	case o3 == 0 && o4 != 0 && o1 * o2 < 0:
+		crossing.point = a.p1
+		crossing.sides[0].needsVertex = false
+	case o4 == 0 && o3 != 0 && o1 * o2 < 0:
+		crossing.point = a.p2
+		crossing.sides[0].needsVertex = false
+		crossing.sides[0].fraction = 1
+	default:
+		return crossing, false
+	}
+	for i, edge := range []gridEdge{a, b} {
+		side := &crossing.sides[i]
+		if !side.needsVertex {
+			continue
+		}
+		side.fraction = fractionAlong(edge.p1, edge.p2, crossing.point)
+		if crossing.point.samePoint(edge.p1) || crossing.point.samePoint(edge.p2) {
+			// Rounding put the crossing at a vertex
+			side.needsVertex = false
+		}
+	}
+	return crossing, true
+}
+
+
+// Cross product giving the turn from p1 to p2 to p3:  positive if counterclockwise, negative
+// if clockwise.  Its magnitude is proportional to the distance of p3 from the line p1-p2.
+func turn(p1, p2, p3 latlongType) int64 {
+	return int64(p2.long - p1.long) * int64(p3.lat - p1.lat) -
+		int64(p2.lat - p1.lat) * int64(p3.long - p1.long)
+}
+
+func sign(value int64) int {
+	switch {
+	case value > 0:
+		return 1
+	case value < 0:
+		return -1
+	}
+	return 0
+}
+
+func fractionAlong(p1, p2, point latlongType) float64 {
+	dLat, dLong := float64(p2.lat - p1.lat), float64(p2.long - p1.long)
+	return (float64(point.lat - p1.lat) * dLat + float64(point.long - p1.long) * dLong) /
+		(dLat * dLat + dLong * dLong)
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a % b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+
+// Returns the locations with vertices inserted at their crossings
+func insertVerticesAtCrossings(locations map[locationIndexType]locationPairs,
+		crossings []geometricCrossing) map[locationIndexType]locationPairs {
+	type insertion struct {
+		offset locationIndexType
+		fraction float64
+		point latlongType
+	}
+	insertions := map[locationIndexType][]insertion{}
+	for _, crossing := range crossings {
+		for _, side := range crossing.sides {
+			if side.needsVertex {
+				insertions[side.locationIndex] = append(insertions[side.locationIndex],
+					insertion{side.offset, side.fraction, crossing.point})
+			}
+		}
+	}
+	modified := map[locationIndexType]locationPairs{}
+	for locationIndex, list := range insertions {
+		sort.Slice(list, func (i, j int) bool {
+			return list[i].offset < list[j].offset ||
+				(list[i].offset == list[j].offset && list[i].fraction < list[j].fraction)
+		})
+		pairs := locations[locationIndex]
+		out := make(locationPairs, 0, len(pairs) + 2 * len(list))
+		next := 0
+		for i := 0; i < len(pairs); i += 2 {
+			out = append(out, pairs[i], pairs[i+1])
+			for ; next < len(list) && int(list[next].offset) == i; next++ {
+				point := list[next].point
+				if !out.latlongPair(len(out) - 2).samePoint(point) {
+					out = append(out, point.lat, point.long)
+				}
+			}
+		}
+		modified[locationIndex] = out
+	}
+	return modified
+}
+
+
+// Replaces the vertices of locations into which crossing vertices were inserted
+func (vd *VectorData) applyInsertedVertices() {
+	inserted := vd.crossingFinder.insertedVertices
+	if len(inserted) == 0 {
+		return
+	}
+	for _, item := range vd.mapItems {
+		if loc, is := item.(*map_locationType); is {
+			if pairs, found := inserted[loc.locIndex]; found && loc.prototypePath == nil {
+				loc.location = pairs
+			}
+		}
+	}
+}
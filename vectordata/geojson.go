@@ -0,0 +1,178 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+
+type geoJsonFeatureCollection struct {
+	Type string `json:"type"`
+	Features []geoJsonFeature `json:"features"`
+}
+
+type geoJsonFeature struct {
+	Type string `json:"type"`
+	Geometry geoJsonGeometry `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geoJsonGeometry struct {
+	Type string `json:"type"`
+	Coordinates any `json:"coordinates"`
+}
+
+
+// Generates a GeoJSON (RFC 7946) feature collection of the map items in all layers.  Each
+// item appears once no matter how many layers or features refer to it.
+func (vd *VectorData) GenerateGeoJson() (string, error) {
+	layers, err := vd.flattenForExport()
+	if err != nil {
+		return "", err
+	}
+	collection := geoJsonFeatureCollection{Type: "FeatureCollection",
+		Features: []geoJsonFeature{}}
+	seen := map[*exportItem]bool{}
+	for _, layer := range layers {
+		for _, ei := range layer.items {
+			if seen[ei] {
+				continue
+			}
+			seen[ei] = true
+			geometry, err := ei.geoJsonGeometry()
+			if err != nil {
+				return "", err
+			}
+			if len(geometry.Type) == 0 {
+				continue
+			}
+			collection.Features = append(collection.Features, geoJsonFeature{
+				Type: "Feature",
+				Geometry: geometry,
+				Properties: ei.geoJsonProperties(),
+			})
+		}
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	err = encoder.Encode(collection)
+	return buf.String(), err
+}
+
+
+func (ei *exportItem) geoJsonGeometry() (geoJsonGeometry, error) {
+	switch ei.item.ItemType() {
+	case mitPath, mitRoute, mitSegment:
+		switch len(ei.lines) {
+		case 0:
+			return geoJsonGeometry{}, nil
+		case 1:
+			return geoJsonGeometry{"LineString", geoJsonPositions(ei.lines[0])}, nil
+		}
+		lines := make([][][]json.Number, len(ei.lines))
+		for i, line := range ei.lines {
+			lines[i] = geoJsonPositions(line)
+		}
+		return geoJsonGeometry{"MultiLineString", lines}, nil
+	case mitPolygon:
+		ring := ei.points
+		if !ring[0].samePoint(ring[len(ring) - 1]) {
+			ring = append(ring, ring[0])
+		}
+		return geoJsonGeometry{"Polygon", [][][]json.Number{geoJsonPositions(ring)}}, nil
+	case mitRectangle:
+		ring := boundingRectangle(ei.points)
+		return geoJsonGeometry{"Polygon", [][][]json.Number{geoJsonPositions(ring)}}, nil
+	case mitMarker, mitCircle:
+		return geoJsonGeometry{"Point", geoJsonPosition(ei.points[0])}, nil
+	}
+	return geoJsonGeometry{}, ei.item.Error("cannot export %s to GeoJSON",
+		ei.item.ItemTypeString())
+}
+
+
+func (ei *exportItem) geoJsonProperties() map[string]any {
+	props := map[string]any{
+		"type": ei.item.ItemTypeString(),
+		"layers": ei.layers,
+	}
+	if name := exportableName(ei.item); len(name) > 0 {
+		props["name"] = name
+	}
+	if len(ei.popup) > 0 {
+		props["popup"] = ei.popup
+	}
+	if len(ei.style) > 0 {
+		style := make(map[string]any, len(ei.style))
+		for key, value := range ei.style {
+			style[key] = value.typedValue()
+		}
+		props["style"] = style
+	}
+	if len(ei.attestations) > 0 {
+		props["attestation"] = ei.attestations
+	}
+	if period := periodOf(ei.item); period != nil {
+		props["from"] = period.from
+		if period.hasEnd {
+			props["to"] = period.to
+		}
+	}
+	if route, is := ei.item.(*mapRouteOrSegmentType); is && route.alternative != nil {
+		props["alternativeTo"] = route.alternative.group.members[0].Name()
+	}
+	if loc, is := ei.item.(*map_locationType); is {
+		if len(loc.html) > 0 {
+			props["html"] = loc.html
+		}
+		if loc.ItemType() == mitCircle {
+			props["radius"] = loc.radius
+			if loc.radiusType == mitPixels {
+				props["radiusUnits"] = "pixels"
+			} else {
+				props["radiusUnits"] = "meters"
+			}
+		}
+	}
+	return props
+}
+
+
+// GeoJSON positions are longitude first.  Use the fixed-point text so as not to introduce
+// floating-point noise into the coordinates.
+func geoJsonPosition(ll latlongType) []json.Number {
+	return []json.Number{json.Number(ll.long.String()), json.Number(ll.lat.String())}
+}
+
+func geoJsonPositions(points []latlongType) [][]json.Number {
+	out := make([][]json.Number, len(points))
+	for i, ll := range points {
+		out[i] = geoJsonPosition(ll)
+	}
+	return out
+}
+
+
+// Closed counterclockwise ring around the extent of the given points
+func boundingRectangle(points []latlongType) []latlongType {
+	min, max := points[0], points[0]
+	for _, ll := range points[1:] {
+		if ll.lat < min.lat {
+			min.lat = ll.lat
+		}
+		if ll.long < min.long {
+			min.long = ll.long
+		}
+		if ll.lat > max.lat {
+			max.lat = ll.lat
+		}
+		if ll.long > max.long {
+			max.long = ll.long
+		}
+	}
+	return []latlongType{min, {min.lat, max.long}, max, {max.lat, min.long}, min}
+}
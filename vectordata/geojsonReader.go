@@ -0,0 +1,339 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"potano.misiones/sexp"
+)
+
+
+type geoJsonInFeature struct {
+	Type string `json:"type"`
+	Geometry *geoJsonInGeometry `json:"geometry"`
+	Properties map[string]any `json:"properties"`
+}
+
+type geoJsonInGeometry struct {
+	Type string `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+type geoJsonReader struct {
+	builder sexp.Builder
+	keys map[string]string
+	items []sexp.LispValue
+}
+
+
+func initialGeojsonKeyMap() map[string]string {
+	return map[string]string{
+		"name": "name",
+		"popup": "popup",
+		"style": "style",
+		"attestation": "attestation",
+		"type": "type",
+	}
+}
+
+
+func (vd *VectorData) setGeojsonKeys(item *mapGeojsonKeysType) error {
+	for key, property := range item.keys {
+		if _, allowed := vd.geojsonKeys[key]; !allowed {
+			return item.Error("unknown GeoJSON key %s", key)
+		}
+		if len(property) == 0 {
+			return item.Error("empty GeoJSON property name for key %s", key)
+		}
+		vd.geojsonKeys[key] = property
+	}
+	return nil
+}
+
+
+// Reads the features of a GeoJSON feature collection as paths, polygons, points, and markers.
+// Configuration items must be read before calling this method in order for the property-key
+// mappings to take effect.
+func (vdr *VectorDataReader) ConsumeGeoJson(filename string, input io.Reader) error {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	lines := newSourceLines(data)
+	gr := &geoJsonReader{builder: sexp.NewBuilder(filename), keys: vdr.data.geojsonKeys}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	lineAtDecoder := func() uint32 {
+		offset := decoder.InputOffset()
+		for offset < int64(len(data)) && data[offset] <= ' ' {
+			offset++
+		}
+		return lines.lineAt(offset)
+	}
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('{') {
+		return gr.builder.Error(lineAtDecoder(), "GeoJSON input is not an object")
+	}
+	var collectionType string
+	for decoder.More() {
+		tok, err := decoder.Token()
+		if err != nil {
+			return gr.builder.Error(lineAtDecoder(), "%s", err)
+		}
+		switch tok {
+		case "type":
+			err = decoder.Decode(&collectionType)
+		case "features":
+			err = gr.readFeatures(decoder, lineAtDecoder)
+		default:
+			var discard json.RawMessage
+			err = decoder.Decode(&discard)
+		}
+		if err != nil {
+			if _, is := err.(sexp.SexpError); is {
+				return err
+			}
+			return gr.builder.Error(lineAtDecoder(), "%s", err)
+		}
+	}
+	if collectionType != "FeatureCollection" {
+		return gr.builder.Error(1, "GeoJSON input is not a FeatureCollection")
+	}
+	if len(gr.items) == 0 {
+		return nil
+	}
+	return vdr.ConsumeList(gr.builder.List(1, "0", gr.items...))
+}
+
+
+func (gr *geoJsonReader) readFeatures(decoder *json.Decoder, lineAt func() uint32) error {
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+		return gr.builder.Error(lineAt(), "GeoJSON features member is not an array")
+	}
+	for decoder.More() {
+		lineno := lineAt()
+		var feature geoJsonInFeature
+		err := decoder.Decode(&feature)
+		if err != nil {
+			return gr.builder.Error(lineno, "%s", err)
+		}
+		err = gr.addFeature(lineno, feature)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+
+func (gr *geoJsonReader) addFeature(lineno uint32, feature geoJsonInFeature) error {
+	if feature.Type != "Feature" {
+		return gr.builder.Error(lineno, "GeoJSON object of type %s is not a Feature",
+			feature.Type)
+	}
+	if feature.Geometry == nil {
+		return nil
+	}
+	name, err := gr.stringProperty(lineno, feature.Properties, "name")
+	if err != nil {
+		return err
+	}
+	if len(name) == 0 {
+		return gr.builder.Error(lineno, "GeoJSON feature has no '%s' property",
+			gr.keys["name"])
+	}
+	name = identifierFromName(name)
+	itemType, err := gr.stringProperty(lineno, feature.Properties, "type")
+	if err != nil {
+		return err
+	}
+	attributes, err := gr.attributeLists(lineno, feature.Properties)
+	if err != nil {
+		return err
+	}
+
+	var parts [][][][]json.Number
+	geometry := feature.Geometry
+	switch geometry.Type {
+	case "Point":
+		var position []json.Number
+		err = json.Unmarshal(geometry.Coordinates, &position)
+		parts = [][][][]json.Number{{{position}}}
+	case "MultiPoint", "LineString":
+		var positions [][]json.Number
+		err = json.Unmarshal(geometry.Coordinates, &positions)
+		if geometry.Type == "LineString" {
+			parts = [][][][]json.Number{{positions}}
+		} else {
+			for _, position := range positions {
+				parts = append(parts, [][][]json.Number{{position}})
+			}
+		}
+	case "MultiLineString", "Polygon":
+		var lines [][][]json.Number
+		err = json.Unmarshal(geometry.Coordinates, &lines)
+		if geometry.Type == "Polygon" {
+			parts = [][][][]json.Number{lines}
+		} else {
+			for _, line := range lines {
+				parts = append(parts, [][][]json.Number{line})
+			}
+		}
+	case "MultiPolygon":
+		err = json.Unmarshal(geometry.Coordinates, &parts)
+	default:
+		return gr.builder.Error(lineno, "unsupported GeoJSON geometry type %s", geometry.Type)
+	}
+	if err != nil {
+		return gr.builder.Error(lineno, "%s", err)
+	}
+	listType, err := gr.listTypeFor(lineno, geometry.Type, itemType)
+	if err != nil {
+		return err
+	}
+	// Points carry no attributes and markers carry only popups
+	for _, attribute := range attributes {
+		if listType == "point" || (listType == "marker" && attribute.Head() != "popup") {
+			return gr.builder.Error(lineno, "%s %s cannot carry the '%s' property", listType,
+				name, gr.keys[attribute.Head()])
+		}
+	}
+
+	for i, part := range parts {
+		partName := name
+		if len(parts) > 1 {
+			partName = fmt.Sprintf("%s_%d", name, i + 1)
+		}
+		if len(part) > 1 {
+			return gr.builder.Error(lineno, "polygon %s has holes, which are not supported",
+				partName)
+		}
+		if len(part) == 0 || len(part[0]) == 0 {
+			return gr.builder.Error(lineno, "polygon %s has no positions", partName)
+		}
+		positions := part[0]
+		if listType == "polygon" && len(positions) > 1 {
+			first, last := positions[0], positions[len(positions) - 1]
+			if len(first) > 1 && len(last) > 1 && first[0] == last[0] &&
+					first[1] == last[1] {
+				positions = positions[:len(positions) - 1]
+			}
+		}
+		items := []sexp.LispValue{gr.builder.Symbol(lineno, partName)}
+		for _, attribute := range attributes {
+			items = append(items, attribute)
+		}
+		for _, position := range positions {
+			if len(position) < 2 {
+				return gr.builder.Error(lineno, "GeoJSON position has fewer than two values")
+			}
+			// GeoJSON positions are longitude first
+			for _, coord := range []json.Number{position[1], position[0]} {
+				text, err := floatToken(coord.String())
+				if err != nil {
+					return gr.builder.Error(lineno, "%s", err)
+				}
+				items = append(items, gr.builder.Float(lineno, text))
+			}
+		}
+		gr.items = append(gr.items, gr.builder.List(lineno, listType, items...))
+	}
+	return nil
+}
+
+
+func (gr *geoJsonReader) listTypeFor(lineno uint32, geometryType, itemType string) (string,
+		error) {
+	var listType string
+	var allowed []string
+	switch geometryType {
+	case "Point", "MultiPoint":
+		listType, allowed = "marker", []string{"marker", "point"}
+	case "LineString", "MultiLineString":
+		listType, allowed = "path", []string{"path"}
+	case "Polygon", "MultiPolygon":
+		listType, allowed = "polygon", []string{"polygon"}
+	}
+	if len(itemType) == 0 {
+		return listType, nil
+	}
+	for _, candidate := range allowed {
+		if itemType == candidate {
+			return itemType, nil
+		}
+	}
+	return "", gr.builder.Error(lineno, "cannot make a %s from GeoJSON %s geometry", itemType,
+		geometryType)
+}
+
+
+// Returns the popup, style, and attestation lists called for by the feature's properties
+func (gr *geoJsonReader) attributeLists(lineno uint32, properties map[string]any) (
+		[]sexp.LispList, error) {
+	var lists []sexp.LispList
+	popup, err := gr.stringProperty(lineno, properties, "popup")
+	if err != nil {
+		return nil, err
+	}
+	if len(popup) > 0 {
+		lists = append(lists, gr.builder.List(lineno, "popup",
+			gr.builder.String(lineno, popup)))
+	}
+	style, err := gr.stringProperty(lineno, properties, "style")
+	if err != nil {
+		return nil, err
+	}
+	if len(style) > 0 {
+		lists = append(lists, gr.builder.List(lineno, "style",
+			gr.builder.Symbol(lineno, style)))
+	}
+	var attestations []string
+	switch value := properties[gr.keys["attestation"]].(type) {
+	case nil:
+	case string:
+		attestations = strings.Fields(value)
+	case []any:
+		for _, elem := range value {
+			str, is := elem.(string)
+			if !is {
+				return nil, gr.builder.Error(lineno,
+					"GeoJSON '%s' property has a non-string element",
+					gr.keys["attestation"])
+			}
+			attestations = append(attestations, str)
+		}
+	default:
+		return nil, gr.builder.Error(lineno,
+			"GeoJSON '%s' property is neither a string nor an array",
+			gr.keys["attestation"])
+	}
+	if len(attestations) > 0 {
+		symbols := make([]sexp.LispValue, len(attestations))
+		for i, attestation := range attestations {
+			symbols[i] = gr.builder.Symbol(lineno, attestation)
+		}
+		lists = append(lists, gr.builder.List(lineno, "attestation", symbols...))
+	}
+	return lists, nil
+}
+
+
+func (gr *geoJsonReader) stringProperty(lineno uint32, properties map[string]any,
+		key string) (string, error) {
+	value, exists := properties[gr.keys[key]]
+	if !exists || value == nil {
+		return "", nil
+	}
+	str, is := value.(string)
+	if !is {
+		return "", gr.builder.Error(lineno, "GeoJSON '%s' property is not a string",
+			gr.keys[key])
+	}
+	return str, nil
+}
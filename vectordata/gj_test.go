@@ -0,0 +1,223 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+
+func checkGeneratedGeoJson(T *testing.T, source string, features []any) {
+	T.Helper()
+	var doc any
+	err := json.Unmarshal([]byte(source), &doc)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	checkAnyValue(T, doc, "collection", map[string]any{
+		"type": "FeatureCollection",
+		"features": features,
+	})
+}
+
+
+
+func Test_generateGeoJson(T *testing.T) {
+	sourceText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features hill theRoad)
+		)
+		(layer two
+			(menuitem "Over here")
+			(features site theRoad)
+		)
+	)
+	(feature hill
+		(popup "Trail along")
+		(style baseStyle)
+		(polygon
+		      29.50 -83.43  29.50 -83.41
+		      29.40 -83.41  29.40 -83.43)
+		(rectangle
+		      29.30 -83.43  29.30 -83.41
+		      29.20 -83.41  29.20 -83.43)
+	)
+	(feature site
+		(circle
+			(popup "side site")
+			(radius 300)
+			30.382195 -83.629487
+		)
+	)
+	(route theRoad
+		(segment
+			(paths path1 path2 mark1)
+		)
+	)
+	(path path1
+		30.350075 -83.507595
+		30.350177 -83.507918
+		30.351541 -83.517636
+	)
+	(path path2
+		(attestation modern_name maybe)
+		30.351842 -83.520299
+		30.351709 -83.519064
+		30.351541 -83.517636
+	)
+	(marker mark1
+		(html ". here it is")
+		30.351842 -83.520299
+	)
+	(config
+		(baseStyle baseStyle
+			"color=#1f78b4"
+			"fill=true"
+			"fillOpacity=0.1"
+		)
+		(attestationType manifestation limit1
+			(attSym modern_name)
+		)
+		(attestationType confidence limit1
+			(attSym forSure)
+			(attSym maybe (modStyle "opacity=0.4"))
+		)
+	)
+	`
+	vd := prepareAndParseStrings(T, sourceText)
+	generated, err := vd.GenerateGeoJson()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	hillStyle := map[string]any{"color": "#1f78b4", "fill": true, "fillOpacity": 0.1}
+	checkGeneratedGeoJson(T, generated, []any{
+		map[string]any{
+			"type": "Feature",
+			"geometry": map[string]any{
+				"type": "Polygon",
+				"coordinates": []any{[]any{
+					[]any{-83.43, 29.5}, []any{-83.41, 29.5},
+					[]any{-83.41, 29.4}, []any{-83.43, 29.4},
+					[]any{-83.43, 29.5},
+				}},
+			},
+			"properties": map[string]any{
+				"type": "polygon",
+				"layers": []any{"Look"},
+				"popup": "Trail along",
+				"style": hillStyle,
+			},
+		},
+		map[string]any{
+			"type": "Feature",
+			"geometry": map[string]any{
+				"type": "Polygon",
+				"coordinates": []any{[]any{
+					[]any{-83.43, 29.2}, []any{-83.41, 29.2},
+					[]any{-83.41, 29.3}, []any{-83.43, 29.3},
+					[]any{-83.43, 29.2},
+				}},
+			},
+			"properties": map[string]any{
+				"type": "rectangle",
+				"layers": []any{"Look"},
+				"popup": "Trail along",
+				"style": hillStyle,
+			},
+		},
+		map[string]any{
+			"type": "Feature",
+			"geometry": map[string]any{
+				"type": "LineString",
+				"coordinates": []any{
+					[]any{-83.507595, 30.350075}, []any{-83.507918, 30.350177},
+					[]any{-83.517636, 30.351541}, []any{-83.519064, 30.351709},
+					[]any{-83.520299, 30.351842},
+				},
+			},
+			"properties": map[string]any{
+				"name": "theRoad",
+				"type": "route",
+				"layers": []any{"Look", "Over here"},
+			},
+		},
+		map[string]any{
+			"type": "Feature",
+			"geometry": map[string]any{
+				"type": "Point",
+				"coordinates": []any{-83.520299, 30.351842},
+			},
+			"properties": map[string]any{
+				"name": "mark1",
+				"type": "marker",
+				"layers": []any{"Look", "Over here"},
+				"html": ". here it is",
+			},
+		},
+		map[string]any{
+			"type": "Feature",
+			"geometry": map[string]any{
+				"type": "Point",
+				"coordinates": []any{-83.629487, 30.382195},
+			},
+			"properties": map[string]any{
+				"type": "circle",
+				"layers": []any{"Over here"},
+				"popup": "side site",
+				"radius": 300,
+				"radiusUnits": "meters",
+			},
+		},
+	})
+}
+
+
+func Test_generateGeoJsonAttestedPath(T *testing.T) {
+	sourceText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features path2)
+		)
+	)
+	(path path2
+		(attestation modern_name maybe)
+		30.351842 -83.520299
+		30.351709 -83.519064
+	)
+	(config
+		(attestationType manifestation limit1
+			(attSym modern_name)
+		)
+		(attestationType confidence limit1
+			(attSym forSure)
+			(attSym maybe (modStyle "opacity=0.4"))
+		)
+	)
+	`
+	vd := prepareAndParseStrings(T, sourceText)
+	generated, err := vd.GenerateGeoJson()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	checkGeneratedGeoJson(T, generated, []any{
+		map[string]any{
+			"type": "Feature",
+			"geometry": map[string]any{
+				"type": "LineString",
+				"coordinates": []any{
+					[]any{-83.520299, 30.351842}, []any{-83.519064, 30.351709},
+				},
+			},
+			"properties": map[string]any{
+				"name": "path2",
+				"type": "path",
+				"layers": []any{"Look"},
+				"style": map[string]any{"opacity": 0.4},
+				"attestation": []any{"modern_name", "maybe"},
+			},
+		},
+	})
+}
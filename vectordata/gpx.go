@@ -0,0 +1,115 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+)
+
+
+type gpxOutDocument struct {
+	XMLName xml.Name `xml:"gpx"`
+	Version string `xml:"version,attr"`
+	Creator string `xml:"creator,attr"`
+	Xmlns string `xml:"xmlns,attr"`
+	Waypoints []gpxOutPoint `xml:"wpt"`
+	Routes []gpxOutRoute `xml:"rte"`
+	Tracks []gpxOutTrack `xml:"trk"`
+}
+
+type gpxOutPoint struct {
+	Lat string `xml:"lat,attr"`
+	Lon string `xml:"lon,attr"`
+	Name string `xml:"name,omitempty"`
+	Desc string `xml:"desc,omitempty"`
+}
+
+type gpxOutRoute struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc,omitempty"`
+	Points []gpxOutPoint `xml:"rtept"`
+}
+
+type gpxOutTrack struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc,omitempty"`
+	Segments []gpxOutSegment `xml:"trkseg"`
+}
+
+type gpxOutSegment struct {
+	Points []gpxOutPoint `xml:"trkpt"`
+}
+
+
+// Generates a GPX 1.1 document for loading the named routes, segments, and paths onto a GPS
+// unit.  Points appear in the same order of travel used for measurement.  Each item becomes a
+// track or, if asRoute is set, a route; a route is written for each discontinuous stretch of
+// the item.  Waypoints along the items are written as <wpt> elements.
+func (vd *VectorData) GenerateGpx(names []string, asRoute bool) (string, error) {
+	doc := gpxOutDocument{
+		Version: "1.1",
+		Creator: "misiones",
+		Xmlns: "http://www.topografix.com/GPX/1/1",
+	}
+	seenWaypoints := map[*map_locationType]bool{}
+	for _, name := range names {
+		collector := &traversalCollector{}
+		err := vd.walkPathsForNamedItem(collector, name, false)
+		if err != nil {
+			return "", err
+		}
+		desc := popupText(vd.mapItems[name])
+		for _, waypoint := range collector.waypoints {
+			if seenWaypoints[waypoint] {
+				continue
+			}
+			seenWaypoints[waypoint] = true
+			wpt := gpxPointFrom(waypoint.location.latlongPair(0))
+			wpt.Name = exportableName(waypoint)
+			wpt.Desc = popupText(waypoint)
+			doc.Waypoints = append(doc.Waypoints, wpt)
+		}
+		if asRoute {
+			for i, line := range collector.lines {
+				rte := gpxOutRoute{Name: name, Desc: desc, Points: gpxPointsFrom(line)}
+				if len(collector.lines) > 1 {
+					rte.Name = fmt.Sprintf("%s_%d", name, i + 1)
+				}
+				doc.Routes = append(doc.Routes, rte)
+			}
+		} else if len(collector.lines) > 0 {
+			trk := gpxOutTrack{Name: name, Desc: desc}
+			for _, line := range collector.lines {
+				trk.Segments = append(trk.Segments, gpxOutSegment{gpxPointsFrom(line)})
+			}
+			doc.Tracks = append(doc.Tracks, trk)
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", " ")
+	err := encoder.Encode(doc)
+	if err != nil {
+		return "", err
+	}
+	buf.WriteString("\n")
+	return buf.String(), nil
+}
+
+
+func gpxPointFrom(ll latlongType) gpxOutPoint {
+	return gpxOutPoint{Lat: ll.lat.String(), Lon: ll.long.String()}
+}
+
+func gpxPointsFrom(line []latlongType) []gpxOutPoint {
+	points := make([]gpxOutPoint, len(line))
+	for i, ll := range line {
+		points[i] = gpxPointFrom(ll)
+	}
+	return points
+}
+
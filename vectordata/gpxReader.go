@@ -0,0 +1,179 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"potano.misiones/sexp"
+)
+
+
+type gpxPoint struct {
+	Lat string `xml:"lat,attr"`
+	Lon string `xml:"lon,attr"`
+	Name string `xml:"name"`
+	Desc string `xml:"desc"`
+}
+
+type gpxTrack struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc"`
+	Segments []struct {
+		Points []gpxPoint `xml:"trkpt"`
+	} `xml:"trkseg"`
+}
+
+type gpxRoute struct {
+	Name string `xml:"name"`
+	Desc string `xml:"desc"`
+	Points []gpxPoint `xml:"rtept"`
+}
+
+type gpxReader struct {
+	builder sexp.Builder
+	items []sexp.LispValue
+}
+
+
+// Reads the tracks, routes, and waypoints of a GPX 1.1 file.  Each track segment and each route
+// becomes a path; each waypoint becomes a marker if it has a description to serve as the
+// marker's popup and a point otherwise.
+func (vdr *VectorDataReader) ConsumeGpx(filename string, input io.Reader) error {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return err
+	}
+	lines := newSourceLines(data)
+	gr := &gpxReader{builder: sexp.NewBuilder(filename)}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := decoder.InputOffset()
+		for offset < int64(len(data)) && data[offset] <= ' ' {
+			offset++
+		}
+		lineno := lines.lineAt(offset)
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return gr.builder.Error(lineno, "%s", err)
+		}
+		start, is := tok.(xml.StartElement)
+		if !is {
+			continue
+		}
+		switch start.Name.Local {
+		case "wpt":
+			var wpt gpxPoint
+			err = decoder.DecodeElement(&wpt, &start)
+			if err == nil {
+				err = gr.addWaypoint(lineno, wpt)
+			}
+		case "trk":
+			var trk gpxTrack
+			err = decoder.DecodeElement(&trk, &start)
+			if err == nil {
+				err = gr.addTrack(lineno, trk)
+			}
+		case "rte":
+			var rte gpxRoute
+			err = decoder.DecodeElement(&rte, &start)
+			if err == nil {
+				err = gr.addPath(lineno, "route", rte.Name, rte.Desc, rte.Points)
+			}
+		}
+		if err != nil {
+			if _, is := err.(sexp.SexpError); is {
+				return err
+			}
+			return gr.builder.Error(lineno, "%s", err)
+		}
+	}
+	if len(gr.items) == 0 {
+		return nil
+	}
+	return vdr.ConsumeList(gr.builder.List(1, "0", gr.items...))
+}
+
+
+func (gr *gpxReader) addWaypoint(lineno uint32, wpt gpxPoint) error {
+	if len(wpt.Name) == 0 {
+		return gr.builder.Error(lineno, "GPX waypoint has no name")
+	}
+	items := []sexp.LispValue{gr.builder.Symbol(lineno, identifierFromName(wpt.Name))}
+	listType := "point"
+	if len(wpt.Desc) > 0 {
+		listType = "marker"
+		items = append(items, gr.builder.List(lineno, "popup",
+			gr.builder.String(lineno, wpt.Desc)))
+	}
+	items, err := gr.appendCoordinates(lineno, items, []gpxPoint{wpt})
+	if err != nil {
+		return err
+	}
+	gr.items = append(gr.items, gr.builder.List(lineno, listType, items...))
+	return nil
+}
+
+
+// Segments without points, which some GPS units write when tracking pauses, are ignored.
+func (gr *gpxReader) addTrack(lineno uint32, trk gpxTrack) error {
+	var segments [][]gpxPoint
+	for _, seg := range trk.Segments {
+		if len(seg.Points) > 0 {
+			segments = append(segments, seg.Points)
+		}
+	}
+	for i, points := range segments {
+		name := trk.Name
+		if len(segments) > 1 && len(name) > 0 {
+			name = fmt.Sprintf("%s_%d", name, i + 1)
+		}
+		err := gr.addPath(lineno, "track", name, trk.Desc, points)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+
+func (gr *gpxReader) addPath(lineno uint32, kind, name, desc string, points []gpxPoint) error {
+	if len(name) == 0 {
+		return gr.builder.Error(lineno, "GPX %s has no name", kind)
+	}
+	items := []sexp.LispValue{gr.builder.Symbol(lineno, identifierFromName(name))}
+	if len(desc) > 0 {
+		items = append(items, gr.builder.List(lineno, "popup",
+			gr.builder.String(lineno, desc)))
+	}
+	items, err := gr.appendCoordinates(lineno, items, points)
+	if err != nil {
+		return err
+	}
+	gr.items = append(gr.items, gr.builder.List(lineno, "path", items...))
+	return nil
+}
+
+
+func (gr *gpxReader) appendCoordinates(lineno uint32, items []sexp.LispValue,
+		points []gpxPoint) ([]sexp.LispValue, error) {
+	for _, pt := range points {
+		for _, coord := range []string{pt.Lat, pt.Lon} {
+			if len(coord) == 0 {
+				return nil, gr.builder.Error(lineno, "GPX point lacks a coordinate")
+			}
+			text, err := floatToken(coord)
+			if err != nil {
+				return nil, gr.builder.Error(lineno, "%s", err)
+			}
+			items = append(items, gr.builder.Float(lineno, text))
+		}
+	}
+	return items, nil
+}
@@ -75,6 +75,7 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			"feature", parser.NameOptional,
 			[]parser.SymbolAction{
 				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
 				{"marker", sexp.TList, "feature"},
 				{"style", sexp.TList, "style"},
 				{"attestation", sexp.TList, "attestation"},
@@ -90,6 +91,7 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			},
 			[]parser.TargetSpec{
 				{"popup", 0, 1, 0},
+				{"period", 0, 1, 0},
 				{"style", 0, 1, 0},
 				{"attestation", 0, 1, 0},
 				{"feature", 0, 0, 1},
@@ -100,11 +102,13 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			[]parser.SymbolAction{
 				{"html", sexp.TList, "html"},
 				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
 				{"", sexp.TFloat, "coordinates"},
 			},
 			[]parser.TargetSpec{
 				{"html", 0, 1, 0},
 				{"popup", 0, 1, 0},
+				{"period", 0, 1, 0},
 				{"coordinates", 2, 2, 1},
 			},
 		},
@@ -166,12 +170,14 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			"path", parser.NameOptional,
 			[]parser.SymbolAction{
 				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
 				{"style", sexp.TList, "style"},
 				{"attestation", sexp.TList, "attestation"},
 				{"", sexp.TFloat, "points"},
 			},
 			[]parser.TargetSpec{
 				{"popup", 0, 1, 1},
+				{"period", 0, 1, 1},
 				{"style", 0, 1, 1},
 				{"attestation", 0, 1, 1},
 				{"points", 4, 0, 2},
@@ -181,10 +187,15 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			"route", parser.NameRequired,
 			[]parser.SymbolAction{
 				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
 				{"style", sexp.TList, "style"},
 				{"attestation", sexp.TList, "attestation"},
 				{"lengthRange", sexp.TList, "lengthRange"},
+				{"mileposts", sexp.TList, "mileposts"},
+				{"travel", sexp.TList, "travel"},
+				{"loop", sexp.TList, "loop"},
 				{"segment", sexp.TList, "feature"},
+				{"alternatives", sexp.TList, "feature"},
 				{"routeSegments", sexp.TList, "feature"},
 				{"point", sexp.TList, "feature"},
 				{"marker", sexp.TList, "feature"},
@@ -193,9 +204,13 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			},
 			[]parser.TargetSpec{
 				{"popup", 0, 1, 1},
+				{"period", 0, 1, 1},
 				{"style", 0, 1, 1},
 				{"attestation", 0, 1, 1},
 				{"lengthRange", 0, 1, 1},
+				{"mileposts", 0, 1, 1},
+				{"travel", 0, 1, 1},
+				{"loop", 0, 1, 1},
 				{"feature", 0, 0, 1},
 			},
 		},
@@ -224,12 +239,14 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			"rectangle", parser.NameOptional,
 			[]parser.SymbolAction{
 				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
 				{"style", sexp.TList, "style"},
 				{"attestation", sexp.TList, "attestation"},
 				{"", sexp.TFloat, "points"},
 			},
 			[]parser.TargetSpec{
 				{"popup", 0, 1, 1},
+				{"period", 0, 1, 1},
 				{"style", 0, 1, 1},
 				{"attestation", 0, 1, 1},
 				{"points", 8, 8, 0},
@@ -239,12 +256,14 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			"polygon", parser.NameOptional,
 			[]parser.SymbolAction{
 				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
 				{"style", sexp.TList, "style"},
 				{"attestation", sexp.TList, "attestation"},
 				{"", sexp.TFloat, "points"},
 			},
 			[]parser.TargetSpec{
 				{"popup", 0, 1, 1},
+				{"period", 0, 1, 1},
 				{"style", 0, 1, 1},
 				{"attestation", 0, 1, 1},
 				{"points", 4, 0, 2},
@@ -254,6 +273,7 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			"circle", parser.NameOptional,
 			[]parser.SymbolAction{
 				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
 				{"style", sexp.TList, "style"},
 				{"attestation", sexp.TList, "attestation"},
 				{"", sexp.TFloat, "points"},
@@ -262,6 +282,7 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			},
 			[]parser.TargetSpec{
 				{"popup", 0, 1, 1},
+				{"period", 0, 1, 1},
 				{"style", 0, 1, 1},
 				{"attestation", 0, 1, 1},
 				{"points", 2, 2, 0},
@@ -290,8 +311,10 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			"segment", parser.NameOptional,
 			[]parser.SymbolAction{
 				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
 				{"style", sexp.TList, "style"},
 				{"attestation", sexp.TList, "attestation"},
+				{"loop", sexp.TList, "loop"},
 				{"path", sexp.TList, "feature"},
 				{"point", sexp.TList, "feature"},
 				{"marker", sexp.TList, "feature"},
@@ -300,6 +323,38 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 			},
 			[]parser.TargetSpec{
 				{"popup", 0, 1, 1},
+				{"period", 0, 1, 1},
+				{"style", 0, 1, 1},
+				{"attestation", 0, 1, 1},
+				{"loop", 0, 1, 1},
+				{"feature", 1, 0, 1},
+			},
+		},
+		{
+			"alternatives", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"alternative", sexp.TList, "feature"},
+			},
+			[]parser.TargetSpec{
+				{"feature", 2, 0, 1},
+			},
+		},
+		{
+			"alternative", parser.NameRequired,
+			[]parser.SymbolAction{
+				{"popup", sexp.TList, "popup"},
+				{"period", sexp.TList, "period"},
+				{"style", sexp.TList, "style"},
+				{"attestation", sexp.TList, "attestation"},
+				{"path", sexp.TList, "feature"},
+				{"point", sexp.TList, "feature"},
+				{"marker", sexp.TList, "feature"},
+				{"circle", sexp.TList, "feature"},
+				{"paths", sexp.TList, "feature"},
+			},
+			[]parser.TargetSpec{
+				{"popup", 0, 1, 1},
+				{"period", 0, 1, 1},
 				{"style", 0, 1, 1},
 				{"attestation", 0, 1, 1},
 				{"feature", 1, 0, 1},
@@ -320,6 +375,11 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 				{"baseStyle", sexp.TList, "configItem"},
 				{"attestationType", sexp.TList, "configItem"},
 				{"lengthUnit", sexp.TList, "configItem"},
+				{"geojsonKeys", sexp.TList, "configItem"},
+				{"earthModel", sexp.TList, "configItem"},
+				{"travelRate", sexp.TList, "configItem"},
+				{"snapTolerance", sexp.TList, "configItem"},
+				{"crossingCheck", sexp.TList, "configItem"},
 			},
 			[]parser.TargetSpec{
 				{"configItem", 1, 0, 1},
@@ -377,6 +437,99 @@ func prepareGrammar() (parser.PreparedGrammar, error) {
 				{"baseUnit", 1, 1, 0},
 			},
 		},
+		{
+			"geojsonKeys", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"", sexp.TString, "keyMapping"},
+			},
+			[]parser.TargetSpec{
+				{"keyMapping", 1, 0, 0},
+			},
+		},
+		{
+			"mileposts", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"", sexp.TNum, "interval"},
+				{"", sexp.TSymbol, "units"},
+				{"style", sexp.TList, "style"},
+			},
+			[]parser.TargetSpec{
+				{"interval", 1, 1, 0},
+				{"units", 1, 1, 0},
+				{"style", 0, 1, 1},
+			},
+		},
+		{
+			"period", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"", sexp.TInt, "years"},
+			},
+			[]parser.TargetSpec{
+				{"years", 1, 2, 0},
+			},
+		},
+		{
+			"loop", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"", sexp.TSymbol, "start"},
+			},
+			[]parser.TargetSpec{
+				{"start", 0, 1, 0},
+			},
+		},
+		{
+			"earthModel", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"", sexp.TSymbol, "modelName"},
+				{"", sexp.TNum, "modelParameter"},
+			},
+			[]parser.TargetSpec{
+				{"modelName", 1, 1, 0},
+				{"modelParameter", 0, 2, 0},
+			},
+		},
+		{
+			"travelRate", parser.NameRequired,
+			[]parser.SymbolAction{
+				{"", sexp.TNum, "amount"},
+				{"", sexp.TSymbol, "unitAndPeriod"},
+			},
+			[]parser.TargetSpec{
+				{"amount", 1, 1, 0},
+				{"unitAndPeriod", 2, 2, 0},
+			},
+		},
+		{
+			"travel", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"", sexp.TNum, "amount"},
+				{"", sexp.TSymbol, "unitAndPeriod"},
+			},
+			[]parser.TargetSpec{
+				{"amount", 0, 1, 0},
+				{"unitAndPeriod", 1, 2, 0},
+			},
+		},
+		{
+			"snapTolerance", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"", sexp.TNum, "meters"},
+				{"", sexp.TSymbol, "mode"},
+			},
+			[]parser.TargetSpec{
+				{"meters", 1, 1, 0},
+				{"mode", 0, 1, 0},
+			},
+		},
+		{
+			"crossingCheck", parser.UnnamedList,
+			[]parser.SymbolAction{
+				{"", sexp.TSymbol, "mode"},
+			},
+			[]parser.TargetSpec{
+				{"mode", 1, 1, 0},
+			},
+		},
 	})
 }
 
@@ -0,0 +1,88 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"strings"
+	"testing"
+)
+
+
+func Test_generateGpx(T *testing.T) {
+	sourceText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(popup "The road")
+		(segment
+			(paths path1 mark1 path2)
+		)
+	)
+	(path path1
+		30.350075 -83.507595
+		30.350177 -83.507918
+	)
+	(path path2
+		30.351541 -83.517636
+		30.350177 -83.507918
+	)
+	(marker mark1
+		(popup "Halfway")
+		30.350177 -83.507918
+	)
+	`
+	vd := prepareAndParseStrings(T, sourceText)
+	for _, tc := range []struct {
+		asRoute bool
+		want string
+	}{
+		{false, `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="misiones" xmlns="http://www.topografix.com/GPX/1/1">
+ <wpt lat="30.350177" lon="-83.507918">
+  <name>mark1</name>
+  <desc>Halfway</desc>
+ </wpt>
+ <trk>
+  <name>theRoad</name>
+  <desc>The road</desc>
+  <trkseg>
+   <trkpt lat="30.350075" lon="-83.507595"></trkpt>
+   <trkpt lat="30.350177" lon="-83.507918"></trkpt>
+   <trkpt lat="30.351541" lon="-83.517636"></trkpt>
+  </trkseg>
+ </trk>
+</gpx>
+`},
+		{true, `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="misiones" xmlns="http://www.topografix.com/GPX/1/1">
+ <wpt lat="30.350177" lon="-83.507918">
+  <name>mark1</name>
+  <desc>Halfway</desc>
+ </wpt>
+ <rte>
+  <name>theRoad</name>
+  <desc>The road</desc>
+  <rtept lat="30.350075" lon="-83.507595"></rtept>
+  <rtept lat="30.350177" lon="-83.507918"></rtept>
+  <rtept lat="30.351541" lon="-83.517636"></rtept>
+ </rte>
+</gpx>
+`},
+	} {
+		got, err := vd.GenerateGpx([]string{"theRoad"}, tc.asRoute)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		if got != tc.want {
+			T.Fatalf("asRoute=%v: wanted\n%s\ngot\n%s", tc.asRoute, tc.want, got)
+		}
+	}
+	_, err := vd.GenerateGpx([]string{"nothing"}, false)
+	if err == nil || !strings.Contains(err.Error(), "unknown map item 'nothing'") {
+		T.Fatalf("expected unknown-item error, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"testing"
+)
+
+
+func Test_importGpx(T *testing.T) {
+	sexpText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features walk_1 walk_2 plan well camp)
+		)
+	)
+	`
+	gpxText := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1">
+  <wpt lat="30.3518420" lon="-83.5202994">
+    <name>well</name>
+    <desc>Old well</desc>
+  </wpt>
+  <wpt lat="30" lon="-83.51">
+    <name>camp</name>
+  </wpt>
+  <trk>
+    <name>walk</name>
+    <trkseg>
+      <trkpt lat="30.350075" lon="-83.507595"><ele>20</ele></trkpt>
+      <trkpt lat="30.350177" lon="-83.507918"></trkpt>
+    </trkseg>
+    <trkseg></trkseg>
+    <trkseg>
+      <trkpt lat="30.351541" lon="-83.517636"></trkpt>
+      <trkpt lat="30.351709" lon="-83.519064"></trkpt>
+    </trkseg>
+  </trk>
+  <rte>
+    <name>plan</name>
+    <desc>Planned route</desc>
+    <rtept lat="30.1" lon="-83.1"></rtept>
+    <rtept lat="30.2" lon="-83.2"></rtept>
+  </rte>
+</gpx>`
+	vd, err := prepareAndParseWithImport(T, sexpText, gpxText, (*VectorDataReader).ConsumeGpx)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	checkParse(T, vd,
+		`→layers '$0' @ infile0:1
+  →layer 'one' @ infile0:2
+      menuitem: 'Look'
+    →features '' @ infile0:4
+        parent: one
+        target names: walk_1 walk_2 plan well camp
+      →path 'walk_1' @ infile1:10
+          location: 30.350075  -83.507595
+                    30.350177  -83.507918
+      →path 'walk_2' @ infile1:10
+          location: 30.351541  -83.517636
+                    30.351709  -83.519064
+      →path 'plan' @ infile1:22
+          popup text: 'Planned route'
+          location: 30.100000  -83.100000
+                    30.200000  -83.200000
+      →marker 'well' @ infile1:3
+          popup text: 'Old well'
+          location: 30.351842  -83.520299
+      →point 'camp' @ infile1:7
+          location: 30.000000  -83.510000`)
+}
+
+
+func Test_importGpxUnnamedTrack(T *testing.T) {
+	gpxText := `<gpx version="1.1">
+  <trk>
+    <trkseg>
+      <trkpt lat="30.350075" lon="-83.507595"></trkpt>
+      <trkpt lat="30.350177" lon="-83.507918"></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+	_, err := prepareAndParseWithImport(T, "(point x 30.0 -83.0)", gpxText,
+		(*VectorDataReader).ConsumeGpx)
+	errmsg := "infile1:2: GPX track has no name"
+	if err == nil || err.Error() != errmsg {
+		T.Fatalf("expected error '%s', got '%v'", errmsg, err)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"potano.misiones/sexp"
+)
+
+
+func prepareAndParseWithImport(T *testing.T, sexpText, importText string,
+		consume func(*VectorDataReader, string, io.Reader) error) (*VectorData, error) {
+	T.Helper()
+	vd, vdReader := prepareReader(T)
+	sourceList, err := sexp.Parse("infile0", strings.NewReader(sexpText))
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	err = vdReader.ConsumeList(sourceList)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	err = consume(vdReader, "infile1", strings.NewReader(importText))
+	if err != nil {
+		return vd, err
+	}
+	return vd, vd.ResolveReferences()
+}
+
+
+
+func Test_importGeoJson(T *testing.T) {
+	sexpText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features trail spring hill camp_1 camp_2)
+		)
+	)
+	(config
+		(baseStyle baseStyle "color=#1f78b4")
+		(geojsonKeys "name=title" "popup=description")
+	)
+	`
+	geojsonText := `{
+	"type": "FeatureCollection",
+	"features": [
+		{
+			"type": "Feature",
+			"properties": {"title": "trail", "description": "Old trail"},
+			"geometry": {
+				"type": "LineString",
+				"coordinates": [[-83.507595, 30.350075], [-83.507918, 30.350177, 12]]
+			}
+		},
+		{
+			"type": "Feature",
+			"properties": {"title": "spring", "description": "Wet"},
+			"geometry": {"type": "Point", "coordinates": [-83.52, 3.0e1]}
+		},
+		{
+			"type": "Feature",
+			"properties": {"title": "hill", "style": "baseStyle"},
+			"geometry": {
+				"type": "Polygon",
+				"coordinates": [[[-83.43, 29.5], [-83.41, 29.5], [-83.41, 29.4],
+					[-83.43, 29.5]]]
+			}
+		},
+		{
+			"type": "Feature",
+			"properties": {"title": "no place", "type": "point"},
+			"geometry": null
+		},
+		{
+			"type": "Feature",
+			"properties": {"title": "camp", "type": "point"},
+			"geometry": {"type": "MultiPoint", "coordinates": [[-83, 30], [-83.1, 30.1]]}
+		}
+	]
+}`
+	vd, err := prepareAndParseWithImport(T, sexpText, geojsonText,
+		(*VectorDataReader).ConsumeGeoJson)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	checkParse(T, vd,
+		`→layers '$0' @ infile0:1
+  →layer 'one' @ infile0:2
+      menuitem: 'Look'
+    →features '' @ infile0:4
+        parent: one
+        target names: trail spring hill camp_1 camp_2
+      →path 'trail' @ infile1:4
+          popup text: 'Old trail'
+          location: 30.350075  -83.507595
+                    30.350177  -83.507918
+      →marker 'spring' @ infile1:11
+          popup text: 'Wet'
+          location: 30.000000  -83.520000
+      →polygon 'hill' @ infile1:16
+          style: baseStyle
+          location: 29.500000  -83.430000
+                    29.500000  -83.410000
+                    29.400000  -83.410000
+      →point 'camp_1' @ infile1:30
+          location: 30.000000  -83.000000
+      →point 'camp_2' @ infile1:30
+          location: 30.100000  -83.100000`)
+}
+
+
+func Test_importGeoJsonErrors(T *testing.T) {
+	sexpText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features x)
+		)
+	)
+	`
+	for _, tc := range []struct {
+		geojson, errmsg string
+	}{
+		{`{"type": "Feature"}`, "infile1:1: GeoJSON input is not a FeatureCollection"},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {},
+				"geometry": {"type": "Point", "coordinates": [-83, 30]}}]}`,
+			"infile1:2: GeoJSON feature has no 'name' property"},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "x"},
+				"geometry": {"type": "Polygon", "coordinates": [
+					[[-83, 30], [-83.1, 30], [-83.1, 30.1]],
+					[[-83.05, 30.01], [-83.06, 30.01], [-83.06, 30.02]]]}}]}`,
+			"infile1:2: polygon x has holes, which are not supported"},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "x"},
+				"geometry": {"type": "Polygon", "coordinates": []}}]}`,
+			"infile1:2: polygon x has no positions"},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "x"},
+				"geometry": {"type": "MultiPolygon", "coordinates": [
+					[[[-83, 30], [-83.1, 30], [-83.1, 30.1]]], []]}}]}`,
+			"infile1:2: polygon x_2 has no positions"},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "x", "type": "polygon"},
+				"geometry": {"type": "LineString", "coordinates": [[-83, 30], [-84, 30]]}}]}`,
+			"infile1:2: cannot make a polygon from GeoJSON LineString geometry"},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "x", "style": "baseStyle"},
+				"geometry": {"type": "Point", "coordinates": [-83, 30]}}]}`,
+			"infile1:2: marker x cannot carry the 'style' property"},
+		{`{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "properties": {"name": "x", "type": "point", "popup": "Camp"},
+				"geometry": {"type": "MultiPoint", "coordinates": [[-83, 30]]}}]}`,
+			"infile1:2: point x cannot carry the 'popup' property"},
+	} {
+		_, err := prepareAndParseWithImport(T, sexpText, tc.geojson,
+			(*VectorDataReader).ConsumeGeoJson)
+		if err == nil {
+			T.Fatalf("expected error %s", tc.errmsg)
+		}
+		if err.Error() != tc.errmsg {
+			T.Fatalf("expected error '%s', got '%s'", tc.errmsg, err)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Helpers shared by the readers of source formats other than S-expressions.  These readers
+// translate their input into S-expressions so that the grammar checks the result just as it
+// checks .sexp files.
+
+
+// Byte offsets of the newlines in a source file, for translating offsets to line numbers
+type sourceLines []int64
+
+func newSourceLines(data []byte) sourceLines {
+	lines := sourceLines{}
+	for i, c := range data {
+		if c == '\n' {
+			lines = append(lines, int64(i))
+		}
+	}
+	return lines
+}
+
+func (sl sourceLines) lineAt(offset int64) uint32 {
+	return uint32(sort.Search(len(sl), func(i int) bool { return sl[i] >= offset }) + 1)
+}
+
+
+// Rewrites a number from a foreign source in the form the S-expression grammar accepts as a
+// float:  no exponent and always a decimal point.
+func floatToken(text string) (string, error) {
+	if strings.ContainsAny(text, "eE") {
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return "", err
+		}
+		text = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	if !strings.Contains(text, ".") {
+		text += ".0"
+	}
+	return text, nil
+}
+
+
+// Converts a free-form name into a legal S-expression symbol by replacing each character that
+// may not appear in a symbol with an underscore.
+func identifierFromName(name string) string {
+	var sb strings.Builder
+	for i, c := range name {
+		if unicode.IsLetter(c) || c == '_' {
+			sb.WriteRune(c)
+		} else if c >= '0' && c <= '9' {
+			if i == 0 {
+				sb.WriteRune('_')
+			}
+			sb.WriteRune(c)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
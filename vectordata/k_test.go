@@ -0,0 +1,184 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+
+const kmlTestSource = `(layers
+	(layer one
+		(menuitem "Look")
+		(features hill path2)
+	)
+)
+(feature hill
+	(popup "Trail <b>along</b>")
+	(style baseStyle)
+	(polygon
+	      29.50 -83.43  29.50 -83.41
+	      29.40 -83.41)
+)
+(path path2
+	(attestation modern_name maybe)
+	30.351842 -83.520299
+	30.351709 -83.519064
+)
+(config
+	(baseStyle baseStyle
+		"color=#1f78b4"
+		"weight=2"
+		"dashArray=4 4"
+	)
+	(attestationType manifestation limit1
+		(attSym modern_name)
+	)
+	(attestationType confidence limit1
+		(attSym forSure)
+		(attSym maybe (modStyle "color=#f00" "opacity=0.4" "fill=false"))
+	)
+)
+`
+
+const kmlTestExpected = `<?xml version="1.0" encoding="UTF-8"?>
+<kml xmlns="http://www.opengis.net/kml/2.2">
+ <Document>
+  <Style id="style1">
+   <LineStyle>
+    <color>ffb4781f</color>
+    <width>2</width>
+   </LineStyle>
+   <PolyStyle>
+    <color>33b4781f</color>
+    <fill>1</fill>
+    <outline>1</outline>
+   </PolyStyle>
+  </Style>
+  <Style id="style2">
+   <LineStyle>
+    <color>660000ff</color>
+    <width>3</width>
+   </LineStyle>
+   <PolyStyle>
+    <color>330000ff</color>
+    <fill>0</fill>
+    <outline>1</outline>
+   </PolyStyle>
+  </Style>
+  <Folder>
+   <name>Look</name>
+   <Placemark>
+    <description>Trail &lt;b&gt;along&lt;/b&gt;</description>
+    <styleUrl>#style1</styleUrl>
+    <ExtendedData>
+     <Data name="dashArray">
+      <value>4 4</value>
+     </Data>
+    </ExtendedData>
+    <Polygon>
+     <outerBoundaryIs>
+      <LinearRing>
+       <coordinates>-83.430000,29.500000 -83.410000,29.500000 -83.410000,29.400000 -83.430000,29.500000</coordinates>
+      </LinearRing>
+     </outerBoundaryIs>
+    </Polygon>
+   </Placemark>
+   <Folder>
+    <name>modern_name maybe</name>
+    <Placemark>
+     <name>path2</name>
+     <styleUrl>#style2</styleUrl>
+     <ExtendedData>
+      <Data name="attestation">
+       <value>modern_name maybe</value>
+      </Data>
+     </ExtendedData>
+     <LineString>
+      <tessellate>1</tessellate>
+      <coordinates>-83.520299,30.351842 -83.519064,30.351709</coordinates>
+     </LineString>
+    </Placemark>
+   </Folder>
+  </Folder>
+ </Document>
+</kml>
+`
+
+
+func Test_generateKml(T *testing.T) {
+	vd := prepareAndParseStrings(T, kmlTestSource)
+	got, err := vd.GenerateKml()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if got != kmlTestExpected {
+		T.Fatalf("wanted\n%s\ngot\n%s", kmlTestExpected, got)
+	}
+}
+
+
+func Test_generateKmz(T *testing.T) {
+	vd := prepareAndParseStrings(T, kmlTestSource)
+	blob, err := vd.GenerateKmz()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(blob), int64(len(blob)))
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if len(archive.File) != 1 || archive.File[0].Name != "doc.kml" {
+		T.Fatalf("expected only doc.kml in archive")
+	}
+	fh, err := archive.File[0].Open()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	got, err := io.ReadAll(fh)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if string(got) != kmlTestExpected {
+		T.Fatalf("wanted\n%s\ngot\n%s", kmlTestExpected, got)
+	}
+}
+
+
+func Test_generateKmlNamedColors(T *testing.T) {
+	source := `(layers
+		(layer one
+			(menuitem "Look")
+			(features path2)
+		)
+	)
+	(path path2
+		(style named)
+		30.351842 -83.520299
+		30.351709 -83.519064
+	)`
+	vd := prepareAndParseStrings(T, source, `(config
+		(baseStyle named "color=DarkGreen" "fillColor=red")
+	)`)
+	got, err := vd.GenerateKml()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	for _, want := range []string{"<color>ff006400</color>", "<color>330000ff</color>"} {
+		if !strings.Contains(got, want) {
+			T.Fatalf("expected %s in\n%s", want, got)
+		}
+	}
+
+	vd = prepareAndParseStrings(T, source, `(config (baseStyle named "color=rgb(0,0,0)"))`)
+	_, err = vd.GenerateKml()
+	if err == nil || !strings.HasSuffix(err.Error(), "style of path path2: color " +
+			"'rgb(0,0,0)' is not a color name or of the form #rrggbb") {
+		T.Fatalf("expected error for unrecognized color, got %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+
+type kmlDocument struct {
+	XMLName xml.Name `xml:"kml"`
+	Xmlns string `xml:"xmlns,attr"`
+	Document kmlDocumentBody `xml:"Document"`
+}
+
+type kmlDocumentBody struct {
+	Styles []kmlStyle `xml:"Style"`
+	Folders []*kmlFolder `xml:"Folder"`
+}
+
+type kmlStyle struct {
+	Id string `xml:"id,attr"`
+	LineStyle kmlLineStyle `xml:"LineStyle"`
+	PolyStyle kmlPolyStyle `xml:"PolyStyle"`
+}
+
+type kmlLineStyle struct {
+	Color string `xml:"color"`
+	Width float64 `xml:"width"`
+}
+
+type kmlPolyStyle struct {
+	Color string `xml:"color"`
+	Fill int `xml:"fill"`
+	Outline int `xml:"outline"`
+}
+
+type kmlFolder struct {
+	Name string `xml:"name"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+	Folders []*kmlFolder `xml:"Folder"`
+}
+
+type kmlPlacemark struct {
+	Name string `xml:"name,omitempty"`
+	Description string `xml:"description,omitempty"`
+	StyleUrl string `xml:"styleUrl,omitempty"`
+	ExtendedData *kmlExtendedData `xml:"ExtendedData"`
+	Point *kmlCoordinates `xml:"Point"`
+	LineString *kmlLineString `xml:"LineString"`
+	Polygon *kmlPolygon `xml:"Polygon"`
+	MultiGeometry *kmlMultiGeometry `xml:"MultiGeometry"`
+}
+
+type kmlExtendedData struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlCoordinates struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlLineString struct {
+	Tessellate int `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+type kmlPolygon struct {
+	OuterBoundary struct {
+		LinearRing kmlCoordinates `xml:"LinearRing"`
+	} `xml:"outerBoundaryIs"`
+}
+
+type kmlMultiGeometry struct {
+	LineStrings []kmlLineString `xml:"LineString"`
+}
+
+type kmlGenerator struct {
+	styles []kmlStyle
+	styleIds map[string]string
+}
+
+
+// LeafletJS defaults for the path options that KML styles can express
+const (
+	leafletDefaultColor = "#3388ff"
+	leafletDefaultWeight = 3
+	leafletDefaultOpacity = 1.0
+	leafletDefaultFillOpacity = 0.2
+)
+
+
+// Generates a KML document of the map items in all layers.  Each layer becomes a folder named
+// from the layer's menuitem; within a layer, items having attestations are placed in subfolders
+// named for the attestation keywords.
+func (vd *VectorData) GenerateKml() (string, error) {
+	layers, err := vd.flattenForExport()
+	if err != nil {
+		return "", err
+	}
+	kg := &kmlGenerator{styleIds: map[string]string{}}
+	doc := kmlDocument{Xmlns: "http://www.opengis.net/kml/2.2"}
+	for _, layer := range layers {
+		folder := &kmlFolder{Name: layer.menuitem}
+		subfolders := map[string]*kmlFolder{}
+		for _, ei := range layer.items {
+			placemark, err := kg.placemark(ei)
+			if err != nil {
+				return "", err
+			}
+			if placemark == nil {
+				continue
+			}
+			target := folder
+			if len(ei.attestations) > 0 {
+				name := strings.Join(ei.attestations, " ")
+				target = subfolders[name]
+				if target == nil {
+					target = &kmlFolder{Name: name}
+					subfolders[name] = target
+					folder.Folders = append(folder.Folders, target)
+				}
+			}
+			target.Placemarks = append(target.Placemarks, *placemark)
+		}
+		doc.Document.Folders = append(doc.Document.Folders, folder)
+	}
+	doc.Document.Styles = kg.styles
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", " ")
+	err = encoder.Encode(doc)
+	if err != nil {
+		return "", err
+	}
+	buf.WriteString("\n")
+	return buf.String(), nil
+}
+
+
+// Generates a KMZ archive holding the KML document as doc.kml
+func (vd *VectorData) GenerateKmz() ([]byte, error) {
+	kml, err := vd.GenerateKml()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	fh, err := archive.Create("doc.kml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = fh.Write([]byte(kml))
+	if err != nil {
+		return nil, err
+	}
+	err = archive.Close()
+	return buf.Bytes(), err
+}
+
+
+// Returns nil for items which have nothing to show
+func (kg *kmlGenerator) placemark(ei *exportItem) (*kmlPlacemark, error) {
+	styleUrl, err := kg.styleUrl(ei.item, ei.style)
+	if err != nil {
+		return nil, err
+	}
+	pm := &kmlPlacemark{
+		Name: exportableName(ei.item),
+		Description: ei.popup,
+		StyleUrl: styleUrl,
+	}
+	var data []kmlData
+	if len(ei.attestations) > 0 {
+		data = append(data, kmlData{"attestation", strings.Join(ei.attestations, " ")})
+	}
+	if dashArray, exists := ei.style["dashArray"]; exists {
+		data = append(data, kmlData{"dashArray", string(dashArray)})
+	}
+	switch ei.item.ItemType() {
+	case mitPath, mitRoute, mitSegment:
+		switch len(ei.lines) {
+		case 0:
+			return nil, nil
+		case 1:
+			pm.LineString = &kmlLineString{1, kmlCoordinateText(ei.lines[0])}
+		default:
+			pm.MultiGeometry = &kmlMultiGeometry{}
+			for _, line := range ei.lines {
+				pm.MultiGeometry.LineStrings = append(pm.MultiGeometry.LineStrings,
+					kmlLineString{1, kmlCoordinateText(line)})
+			}
+		}
+	case mitPolygon:
+		ring := ei.points
+		if !ring[0].samePoint(ring[len(ring) - 1]) {
+			ring = append(ring, ring[0])
+		}
+		pm.Polygon = &kmlPolygon{}
+		pm.Polygon.OuterBoundary.LinearRing.Coordinates = kmlCoordinateText(ring)
+	case mitRectangle:
+		pm.Polygon = &kmlPolygon{}
+		pm.Polygon.OuterBoundary.LinearRing.Coordinates =
+			kmlCoordinateText(boundingRectangle(ei.points))
+	case mitMarker, mitCircle:
+		pm.Point = &kmlCoordinates{kmlCoordinateText(ei.points[:1])}
+		if loc := ei.item.(*map_locationType); loc.ItemType() == mitCircle {
+			units := "meters"
+			if loc.radiusType == mitPixels {
+				units = "pixels"
+			}
+			data = append(data, kmlData{"radius", strconv.Itoa(loc.radius)},
+				kmlData{"radiusUnits", units})
+		}
+	default:
+		return nil, ei.item.Error("cannot export %s to KML", ei.item.ItemTypeString())
+	}
+	if len(data) > 0 {
+		pm.ExtendedData = &kmlExtendedData{data}
+	}
+	return pm, nil
+}
+
+
+// Returns the URL of the shared style for the given properties of the item, creating the style
+// if needed
+func (kg *kmlGenerator) styleUrl(item mapItemType, props cssPropertyMap) (string, error) {
+	if len(props) == 0 {
+		return "", nil
+	}
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(key + "=" + string(props[key]) + ";")
+	}
+	id, exists := kg.styleIds[sb.String()]
+	if !exists {
+		id = fmt.Sprintf("style%d", len(kg.styles) + 1)
+		style, err := kmlStyleFrom(id, props)
+		if err != nil {
+			return "", item.Error("style of %s %s: %s", item.ItemTypeString(), item.Name(), err)
+		}
+		kg.styleIds[sb.String()] = id
+		kg.styles = append(kg.styles, style)
+	}
+	return "#" + id, nil
+}
+
+
+func kmlStyleFrom(id string, props cssPropertyMap) (kmlStyle, error) {
+	floatProp := func(key string, dflt float64) float64 {
+		if value, exists := props[key]; exists {
+			if f, err := value.asFloat(); err == nil {
+				return f
+			}
+		}
+		return dflt
+	}
+	color := leafletDefaultColor
+	if value, exists := props["color"]; exists {
+		color = string(value)
+	}
+	fillColor := color
+	if value, exists := props["fillColor"]; exists {
+		fillColor = string(value)
+	}
+	fill := 1
+	if value, exists := props["fill"]; exists {
+		if b, err := value.asBool(); err == nil && !b {
+			fill = 0
+		}
+	}
+	lineColor, err := kmlColor(color, floatProp("opacity", leafletDefaultOpacity))
+	if err != nil {
+		return kmlStyle{}, err
+	}
+	polyColor, err := kmlColor(fillColor, floatProp("fillOpacity", leafletDefaultFillOpacity))
+	if err != nil {
+		return kmlStyle{}, err
+	}
+	return kmlStyle{
+		Id: id,
+		LineStyle: kmlLineStyle{
+			Color: lineColor,
+			Width: floatProp("weight", leafletDefaultWeight),
+		},
+		PolyStyle: kmlPolyStyle{
+			Color: polyColor,
+			Fill: fill,
+			Outline: 1,
+		},
+	}, nil
+}
+
+
+// Converts a CSS color name or a color of the form #rgb or #rrggbb to the KML aabbggrr form
+func kmlColor(color string, opacity float64) (string, error) {
+	given := color
+	if hex, exists := cssNamedColors[strings.ToLower(color)]; exists {
+		color = hex
+	}
+	if len(color) == 4 && color[0] == '#' {
+		color = string([]byte{'#', color[1], color[1], color[2], color[2], color[3], color[3]})
+	}
+	valid := len(color) == 7 && color[0] == '#'
+	if valid {
+		_, err := strconv.ParseUint(color[1:], 16, 32)
+		valid = err == nil
+	}
+	if !valid {
+		return "", fmt.Errorf("color '%s' is not a color name or of the form #rrggbb", given)
+	}
+	color = strings.ToLower(color)
+	if opacity < 0 {
+		opacity = 0
+	} else if opacity > 1 {
+		opacity = 1
+	}
+	alpha := int(opacity * 255 + 0.5)
+	return fmt.Sprintf("%02x%s%s%s", alpha, color[5:7], color[3:5], color[1:3]), nil
+}
+
+
+// KML coordinates are longitude first
+func kmlCoordinateText(points []latlongType) string {
+	coords := make([]string, len(points))
+	for i, ll := range points {
+		coords[i] = ll.long.String() + "," + ll.lat.String()
+	}
+	return strings.Join(coords, " ")
+}
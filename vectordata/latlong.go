@@ -65,6 +65,20 @@ func (lp locationPairs) asReverseFloatSlice() []float64 {
 	return out
 }
 
+func (lp locationPairs) latlongPairs(reverse bool) []latlongType {
+	out := make([]latlongType, len(lp) >> 1)
+	lastOut := len(out) - 1
+	for i := range out {
+		pair := lp.latlongPair(i << 1)
+		if reverse {
+			out[lastOut - i] = pair
+		} else {
+			out[i] = pair
+		}
+	}
+	return out
+}
+
 
 func (ll latlongType) samePoint(ll2 latlongType) bool {
 	return ll.lat == ll2.lat && ll.long == ll2.long
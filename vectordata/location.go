@@ -15,6 +15,7 @@ type map_locationType struct {
 	html string
 	style *mapStyleType
 	attestation *mapAttestationType
+	period *mapPeriodType
 	radius, radiusType int
 	location locationPairs
 	vd *VectorData
@@ -107,6 +108,10 @@ func (ml *map_locationType) setAttestation(attestation *mapAttestationType) {
 	ml.attestation = attestation
 }
 
+func (ml *map_locationType) setPeriod(period *mapPeriodType) {
+	ml.period = period
+}
+
 func (ml *map_locationType) setRadius(radius *mapRadiusType) {
 	ml.radius = radius.radius
 	ml.radiusType = radius.ItemType()
@@ -183,7 +188,6 @@ func countRegisteredLocations(vd *VectorData) locationIndexType {
 
 type mapRadiusType struct {
 	mapItemCore
-	itemType int
 	radius int
 }
 
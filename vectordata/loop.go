@@ -0,0 +1,203 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+
+	"potano.misiones/great"
+	"potano.misiones/sexp"
+)
+
+// A route or segment marked with (loop) is a circuit whose last component returns to the start
+// of its first, as a visita circuit returns to the cabecera.  Its two components may meet at
+// both of their ends without ambiguity, its threaded ends must coincide, and distances along it
+// are taken from its start waypoint, if it names one, wrapping around past the closing point.
+
+
+type mapLoopType struct {
+	mapItemCore
+	start string
+	route *mapRouteOrSegmentType
+}
+
+func newMapLoop(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	ml := &mapLoopType{route: parent.(*mapRouteOrSegmentType)}
+	ml.source = source
+	ml.name = parent.Name()
+	ml.itemType = mitLoop
+	ml.route.loop = ml
+	return ml, nil
+}
+
+func (ml *mapLoopType) addScalars(targetName string, scalars []sexp.LispScalar) error {
+	switch targetName {
+	case "start":
+		ml.start = scalars[0].String()
+	}
+	return nil
+}
+
+
+// Returns the loop marking of the item.  An anonymous segment which is the only component of a
+// loop is itself the loop.
+func (vd *VectorData) loopOf(item mapItemType) *mapLoopType {
+	var route *mapRouteOrSegmentType
+	switch item := item.(type) {
+	case *mapRouteOrSegmentType:
+		route = item
+	case *threadableMapItemReference:
+		return vd.loopOf(item.item)
+	default:
+		return nil
+	}
+	if route.loop != nil {
+		return route.loop
+	}
+	if len(exportableName(route)) > 0 {
+		return nil
+	}
+	for _, referrer := range route.Referrers() {
+		parent, is := vd.mapItems[referrer].(*mapRouteOrSegmentType)
+		if is && len(parent.routeComponents()) == 1 {
+			if loop := vd.loopOf(parent); loop != nil {
+				return loop
+			}
+		}
+	}
+	return nil
+}
+
+
+// Reports a loop whose threaded course does not return to its start or whose start waypoint
+// is not on the loop
+func (vd *VectorData) checkLoopClosure(picked pickedItem) {
+	item := picked.item.(*mapRouteOrSegmentType)
+	if item.loop == nil {
+		return
+	}
+	if !picked.startPoint.samePoint(picked.endPoint) {
+		vd.recordDeferredError(item.loop.Error("%s %s is a loop but ends at (%s,%s), not at " +
+			"its start (%s,%s)", item.ItemTypeString(), item.Name(), picked.endPoint.lat,
+			picked.endPoint.long, picked.startPoint.lat, picked.startPoint.long))
+	}
+	start := item.loop.start
+	if len(start) == 0 {
+		return
+	}
+	target, exists := vd.mapItems[start]
+	loc, is := target.(*map_locationType)
+	switch {
+	case !exists:
+		vd.recordDeferredError(item.loop.Error("loop start '%s' is not defined", start))
+	case !is || !loc.isPoint():
+		vd.recordDeferredError(item.loop.Error("loop start %s '%s' is not a point, marker, " +
+			"or circle", target.ItemTypeString(), start))
+	case !picked.passesThrough(loc.pointAtOffset(0)):
+		vd.recordDeferredError(item.loop.Error("loop start %s '%s' is not on %s %s",
+			loc.ItemTypeString(), start, item.ItemTypeString(), item.Name()))
+	}
+}
+
+// Tells whether the point is among the vertices of the picked item as threaded
+func (pi pickedItem) passesThrough(point latlongType) bool {
+	if len(pi.children) > 0 {
+		for _, child := range pi.children {
+			if child.passesThrough(point) {
+				return true
+			}
+		}
+		return false
+	}
+	loc, is := pi.item.(*map_locationType)
+	if !is {
+		return false
+	}
+	from, to := pi.startOffset, pi.endOffset
+	if from > to {
+		from, to = to, from
+	}
+	for offset := from; offset <= to; offset += 2 {
+		if loc.pointAtOffset(offset).samePoint(point) {
+			return true
+		}
+	}
+	return false
+}
+
+
+// Locates the start of measurement along the named item:  the given place if any, else the
+// start waypoint of a loop, else the start of the item.  Returns the distance of the start from
+// the start of the threaded item, the length of the item, and whether the item is a loop.
+func (vd *VectorData) measurementStart(itemName string, from *RoutePlace) (float64, float64,
+		bool, error) {
+	item, exists := vd.mapItems[itemName]
+	if !exists {
+		return 0, 0, false, fmt.Errorf("unknown map item '%s'", itemName)
+	}
+	loop := vd.loopOf(item)
+	if from == nil && loop != nil && len(loop.start) > 0 {
+		from = &RoutePlace{kind: placeAtWaypoint, name: loop.start}
+	}
+	if from == nil && loop == nil {
+		return 0, 0, false, nil
+	}
+	indexer := &routeIndexer{earth: vd.earth, waypoints: map[string]float64{}}
+	err := walkPathsForItem(indexer, item, false)
+	if err != nil || from == nil {
+		return 0, indexer.meters, loop != nil, err
+	}
+	start, _, err := indexer.locate(vd, itemName, *from)
+	return start, indexer.meters, loop != nil, err
+}
+
+
+// Returns the distance the given distance past the start of measurement, wrapping around the
+// closing point of a loop.  A distance of more than the whole circuit is not allowed.
+func (vd *VectorData) distanceFromStart(itemName string, start, total, distance float64,
+		isLoop bool) (float64, error) {
+	if !isLoop {
+		return start + distance, nil
+	}
+	if distance > total {
+		item := vd.mapItems[itemName]
+		return 0, fmt.Errorf("%s '%s' is a loop only %.1f meters (%.2f miles) around",
+			item.ItemTypeString(), itemName, total, total / great.METERS_PER_MILE)
+	}
+	target := start + distance
+	if target > total {
+		target -= total
+	}
+	return target, nil
+}
+
+// Converts a distance from the start of the threaded item to one from the start of measurement,
+// choosing the turn of a loop nearest the expected distance
+func distanceAroundLoop(meters, start, total, expected float64, isLoop bool) float64 {
+	meters -= start
+	if isLoop {
+		if meters - expected > total / 2 {
+			meters -= total
+		} else if expected - meters > total / 2 {
+			meters += total
+		}
+	}
+	return meters
+}
+
+
+func distinctPoints(points []latlongType) []latlongType {
+	var distinct []latlongType
+	for _, point := range points {
+		known := false
+		for _, other := range distinct {
+			known = known || other.samePoint(point)
+		}
+		if !known {
+			distinct = append(distinct, point)
+		}
+	}
+	return distinct
+}
@@ -0,0 +1,249 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+
+// A triangle of paths a, b, and c from and back to pp, also drawn as path d (b and c) and as
+// the closed path e (all three), with r off the loop
+const loopPaths = `
+	(path a 30.0 -83.0 30.0 -83.01)
+	(path b 30.0 -83.01 30.01 -83.01)
+	(path c 30.01 -83.01 30.0 -83.0)
+	(path d 30.0 -83.01 30.01 -83.01 30.0 -83.0)
+	(path e 30.0 -83.0 30.0 -83.01 30.01 -83.01 30.0 -83.0)
+	(point pp 30.0 -83.0)
+	(point q 30.01 -83.01)
+	(point r 30.005 -83.0)
+	`
+
+const loopLength = 3546.8
+const loopLengthA, loopLengthB = 962.6, 1112.9
+
+
+func Test_loopThreading(T *testing.T) {
+	for _, paths := range []string{"a b c", "c b a", "a d", "d a", "e", "pp a b c"} {
+		vd := prepareAndParseStrings(T, `(layers
+			(layer one
+				(menuitem "Look")
+				(features circuit a b c d e pp q r)
+			)
+		)
+		(route circuit
+			(loop)
+			(segment
+				(paths ` + paths + `)
+			)
+		)` + loopPaths)
+		route := vd.mapItems["circuit"].(*mapRouteOrSegmentType)
+		if !route.startPoint.samePoint(route.endPoint) {
+			T.Fatalf("%s: loop runs from %v to %v", paths, route.startPoint, route.endPoint)
+		}
+		meters, err := vd.MeasurePath("circuit")
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		compareTestLengths(T, paths, loopLength, meters)
+	}
+}
+
+
+func Test_loopErrors(T *testing.T) {
+	prepareAndParseStringsExpectingError(T, "deferred threading errors:\ninfile0:8: route " +
+		"circuit is a loop but ends at (30.010000,-83.010000), not at its start " +
+		"(30.000000,-83.000000)", `(layers
+		(layer one
+			(menuitem "Look")
+			(features circuit a b c d e pp q r)
+		)
+	)
+	(route circuit
+		(loop)
+		(segment
+			(paths a b)
+		)
+	)` + loopPaths)
+	prepareAndParseStringsExpectingError(T, "deferred threading errors:\ninfile0:9: path d " +
+		"connects with path a at multiple points (d is defined at infile0:15)",
+		`(layers
+		(layer one
+			(menuitem "Look")
+			(features circuit a b c d e pp q r)
+		)
+	)
+	(route circuit
+		(segment
+			(paths a d)
+		)
+	)` + loopPaths)
+	for _, test := range []struct {
+		loop, expected string
+	}{
+		{"(loop qq)", "loop start 'qq' is not defined"},
+		{"(loop a)", "loop start path 'a' is not a point, marker, or circle"},
+		{"(loop r)", "loop start point 'r' is not on route circuit"},
+	} {
+		prepareAndParseStringsExpectingError(T, "deferred threading errors:\ninfile0:8: " +
+			test.expected, `(layers
+			(layer one
+				(menuitem "Look")
+				(features circuit a b c d e pp q r)
+			)
+		)
+		(route circuit
+			` + test.loop + `
+			(segment
+				(paths a b c)
+			)
+		)` + loopPaths)
+	}
+}
+
+
+func Test_loopMeasurement(T *testing.T) {
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features circuit a b c d e pp q r)
+		)
+	)
+	(route circuit
+		(loop q)
+		(segment
+			(paths a b c)
+		)
+	)` + loopPaths)
+	// From q the loop runs along c, then a, then b
+	pos, err := vd.MeasurePathToPosition("circuit", 500)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if pos.PathName != "c" || pos.Distance != 500 {
+		T.Fatalf("expected 500 meters along c, got %.1f along %s", pos.Distance, pos.PathName)
+	}
+	fromStart := loopLength - loopLengthA - loopLengthB
+	pos, err = vd.MeasurePathToPosition("circuit", fromStart + loopLengthA + 100)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if pos.PathName != "b" || math.Abs(pos.Lat - 30.0009) > 1E-4 {
+		T.Fatalf("expected about 100 meters along b, got %.6f along %s", pos.Lat,
+			pos.PathName)
+	}
+	_, _, distance, pathName, index, err := vd.MeasurePathUpTo("circuit", fromStart + 10)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if pathName != "a" || index != 0 {
+		T.Fatalf("expected point 0 of a, got point %d of %s", index, pathName)
+	}
+	compareTestLengths(T, "vertex after closing point", fromStart, distance)
+	_, err = vd.MeasurePathToPosition("circuit", loopLength + 10)
+	if err == nil || err.Error() != "route 'circuit' is a loop only 3546.8 meters " +
+			"(2.20 miles) around" {
+		T.Fatalf("expected error for distance beyond the loop, got %v", err)
+	}
+
+	pp := RoutePlace{kind: placeAtWaypoint, name: "pp"}
+	q := RoutePlace{kind: placeAtWaypoint, name: "q"}
+	interval, err := vd.MeasureBetween("circuit", q, pp)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "q to pp", fromStart, interval.Meters)
+	interval, err = vd.MeasureBetween("circuit", pp, q)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "pp to q", loopLengthA + loopLengthB, interval.Meters)
+	compareTestLengths(T, "pp from start", fromStart, interval.FromMeters)
+
+	pos, err = vd.MeasurePathToPositionFrom("circuit", &pp, loopLengthA + 100)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if pos.PathName != "b" || math.Abs(pos.Lat - 30.0009) > 1E-4 {
+		T.Fatalf("expected about 100 meters along b from pp, got %.6f along %s", pos.Lat,
+			pos.PathName)
+	}
+}
+
+
+func Test_loopGeneration(T *testing.T) {
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features circuit a b c d e pp q r)
+		)
+	)
+	(route circuit
+		(loop pp)
+		(mileposts 1 kilometers)
+		(segment
+			(paths e)
+		)
+	)` + loopPaths,
+		`(config (lengthUnit kilometers 1000 meters))`)
+	route := vd.mapItems["circuit"].(*mapRouteOrSegmentType)
+	if len(route.mileposts.markers) != 3 {
+		T.Fatalf("expected 3 mileposts, got %d", len(route.mileposts.markers))
+	}
+	_, err := vd.generateJson(FixedPointEncoding, nil)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	geojson, err := vd.GenerateGeoJson()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	want := `"coordinates":[[-83.000000,30.000000],[-83.010000,30.000000],` +
+		`[-83.010000,30.010000],[-83.000000,30.000000]]},"properties":{"layers":["Look"],` +
+		`"name":"circuit"`
+	if !strings.Contains(geojson, want) {
+		T.Fatalf("expected the loop to return to its start, got %s", geojson)
+	}
+}
+
+
+func Test_loopMileposts(T *testing.T) {
+	// Mileposts count from the start waypoint q, not from the threaded start at pp
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features circuit q)
+		)
+	)
+	(route circuit
+		(loop q)
+		(mileposts 1 kilometers)
+		(segment
+			(paths a b c)
+		)
+	)
+	(path a 30.0 -83.0 30.0 -83.01)
+	(path b 30.0 -83.01 30.01 -83.01)
+	(path c 30.01 -83.01 30.0 -83.0)
+	(point q 30.01 -83.01)
+	`, `(config (lengthUnit kilometers 1000 meters))`)
+	markers := vd.mapItems["circuit"].(*mapRouteOrSegmentType).mileposts.markers
+	if len(markers) != 3 {
+		T.Fatalf("expected 3 mileposts, got %d", len(markers))
+	}
+	for i, marker := range markers {
+		pos, err := vd.MeasurePathToPosition("circuit", float64(i + 1) * 1000)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		got := marker.location.asFloatSlice()
+		if math.Abs(got[0] - pos.Lat) > 1E-6 || math.Abs(got[1] - pos.Long) > 1E-6 {
+			T.Fatalf("milepost %d: expected (%.6f,%.6f), got (%.6f,%.6f)", i + 1, pos.Lat,
+				pos.Long, got[0], got[1])
+		}
+	}
+}
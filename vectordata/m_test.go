@@ -1503,3 +1503,45 @@ func Test_measureSecondPathSpurFirstMainSegmentReversed(T *testing.T) {
 	}
 }
 
+
+
+func Test_measurePathToPosition(T *testing.T) {
+	sourceText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(segment roadSeg1
+			(paths path1 path2Reversed)
+		)
+	)
+	` + path1 + path2Reversed
+	vd := prepareAndParseStrings(T, sourceText)
+	for _, tst := range []struct{dist, lat, long, bearing float64; name string; from, to int} {
+		{600, 30.351025, -83.513743, 278.73, "path1", 2, 3},
+		{1000, 30.351568, -83.517863, 277.76, "path2Reversed", 4, 3},
+		{1100, 30.351689, -83.518895, 277.76, "path2Reversed", 4, 3},
+		{1235, 30.351841, -83.520290, 275.00, "path2Reversed", 1, 0},
+	} {
+		pos, err := vd.MeasurePathToPosition("theRoad", tst.dist)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		if math.Abs(pos.Lat - tst.lat) > 5E-7 || math.Abs(pos.Long - tst.long) > 5E-7 ||
+				math.Abs(pos.Bearing - tst.bearing) > 0.005 || pos.Distance != tst.dist ||
+				pos.PathName != tst.name || pos.FromIndex != tst.from ||
+				pos.ToIndex != tst.to {
+			T.Fatalf("wanted %.1f @ [%.6f %.6f] bearing %.2f in %s #%d-%d\n" +
+				"got %.1f @ [%.6f %.6f] bearing %.2f in %s #%d-%d",
+				tst.dist, tst.lat, tst.long, tst.bearing, tst.name, tst.from, tst.to,
+				pos.Distance, pos.Lat, pos.Long, pos.Bearing, pos.PathName,
+				pos.FromIndex, pos.ToIndex)
+		}
+	}
+	_, err := vd.MeasurePathToPosition("theRoad", 1300)
+	if err == nil || err.Error() != "route 'theRoad' is only 1235.9 meters (0.77 miles) long" {
+		T.Fatalf("expected error for distance beyond end of route, got %v", err)
+	}
+}
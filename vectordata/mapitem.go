@@ -42,10 +42,22 @@ func (rv readerValet) NewChild(listType, listName string, source sexp.ValueSourc
 		constructor = newMapRoute
 	case "lengthRange":
 		constructor = newMapLengthRange
+	case "mileposts":
+		constructor = newMapMileposts
+	case "travel", "travelRate":
+		constructor = newMapTravelRate
+	case "loop":
+		constructor = newMapLoop
 	case "radius", "pixels":
 		constructor = newMapRadius
+	case "period":
+		constructor = newMapPeriod
 	case "segment":
 		constructor = newMapSegment
+	case "alternatives":
+		constructor = newMapAlternatives
+	case "alternative":
+		constructor = newMapAlternative
 	case "config":
 		constructor = newMapConfig
 	case "baseStyle", "modStyle":
@@ -56,6 +68,14 @@ func (rv readerValet) NewChild(listType, listName string, source sexp.ValueSourc
 		constructor = newAttSym
 	case "lengthUnit":
 		constructor = newMapLengthUnit
+	case "geojsonKeys":
+		constructor = newMapGeojsonKeys
+	case "earthModel":
+		constructor = newMapEarthModel
+	case "snapTolerance":
+		constructor = newMapSnapTolerance
+	case "crossingCheck":
+		constructor = newMapCrossingCheck
 	}
 	newItem, err := constructor(rv.doc, rv.curItem, listType, listName, source)
 	if err != nil {
@@ -100,6 +120,12 @@ func (rv readerValet) SetList(targetName, listType string, source sexp.ValueSour
 		}
 	case "feature":
 		curItem.addFeature(newChild)
+	case "period":
+		if asPeriod, is := newChild.(*mapPeriodType); !is {
+			return source.Error("not a period")
+		} else {
+			curItem.setPeriod(asPeriod)
+		}
 	case "html":
 		if asHtml, is := newChild.(*map_textType); !is {
 			return source.Error("not an html")
@@ -117,7 +143,7 @@ func (rv readerValet) SetList(targetName, listType string, source sexp.ValueSour
 		if err != nil {
 			return err
 		}
-	case "lengthRange":
+	case "lengthRange", "mileposts", "travel", "loop":
 	default:
 		return source.Error("** internal error **: unhandled target type %s", targetName)
 	}
@@ -181,6 +207,7 @@ func (mic *mapItemCore) setMenuitem(layer *map_textType) {}
 func (mic *mapItemCore) setPopup(popup *mapPopupType) {}
 func (mic *mapItemCore) setStyle(style *mapStyleType) {}
 func (mic *mapItemCore) setAttestation(attestation *mapAttestationType) {}
+func (mic *mapItemCore) setPeriod(period *mapPeriodType) {}
 func (mic *mapItemCore) setHtml(html *map_textType) {}
 func (mic *mapItemCore) setRadius(radius *mapRadiusType) {}
 func (mic *mapItemCore) addFeature(feature mapItemType) {}
@@ -230,6 +257,7 @@ type mapFeatureType struct {
 	popup *mapPopupType
 	style *mapStyleType
 	attestation *mapAttestationType
+	period *mapPeriodType
 	features []mapItemType
 }
 
@@ -258,6 +286,10 @@ func (mf *mapFeatureType) setAttestation(attestation *mapAttestationType) {
 	mf.attestation = attestation
 }
 
+func (mf *mapFeatureType) setPeriod(period *mapPeriodType) {
+	mf.period = period
+}
+
 func (mf *mapFeatureType) addFeature(feature mapItemType) {
 	mf.features = append(mf.features, feature)
 }
@@ -0,0 +1,112 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"math"
+	"testing"
+)
+
+
+func Test_parseRoutePlace(T *testing.T) {
+	for _, test := range []struct{spec, want, err string} {
+		{"mission1", "mission1", ""},
+		{" 30.35, -83.5 ", "30.350000,-83.500000", ""},
+		{"2.5", "4023.4 meters", ""},
+		{"30.35,", "", "'30.35,' is not a valid latitude,longitude pair"},
+		{"95,-83.5", "", "'95,-83.5' is not a valid latitude,longitude pair"},
+		{"-1", "", "distance -1 is negative"},
+		{"", "", "empty route place"},
+	} {
+		place, err := ParseRoutePlace(test.spec, 1609.344)
+		if len(test.err) > 0 {
+			if err == nil || err.Error() != test.err {
+				T.Fatalf("%s: expected error '%s', got '%v'", test.spec, test.err, err)
+			}
+		} else if err != nil {
+			T.Fatalf("%s: %s", test.spec, err)
+		} else if place.String() != test.want {
+			T.Fatalf("%s: expected %s, got %s", test.spec, test.want, place)
+		}
+	}
+}
+
+
+func Test_measureBetween(T *testing.T) {
+	sourceText := `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad mission)
+		)
+	)
+	(route theRoad
+		(segment
+			(paths pointStartPath1 path1 path1End path2)
+		)
+	)
+	(point path1End 30.351541 -83.517636)
+	(marker mission 30.352441 -83.517636)
+	` + pointStartPath1 + path1 + path2
+	vd := prepareAndParseStrings(T, sourceText)
+	for _, test := range []struct{from, to string; meters, fromOff, toOff float64} {
+		{"pointStartPath1", "path1End", path1_length, 0, 0},
+		{"path1End", "pointStartPath1", path1_length, 0, 0},
+		{"pointStartPath1", "500", 500, 0, 0},
+		{"30.351541,-83.517636", "1200", 1200 - path1_length, 0, 0},
+		{"path1End", "mission", 13.5, 0, 99.2},
+		// 10 meters north of the middle of the first step of path2, which heads west-northwest
+		{"path1End", "30.351715,-83.518350", 70.5, 0, 9.9},
+	} {
+		from, err := ParseRoutePlace(test.from, 1)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		to, err := ParseRoutePlace(test.to, 1)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		interval, err := vd.MeasureBetween("theRoad", from, to)
+		if err != nil {
+			T.Fatalf("%s to %s: %s", test.from, test.to, err)
+		}
+		if math.Abs(interval.Meters - test.meters) > 0.05 ||
+				math.Abs(interval.FromOffRoute - test.fromOff) > 0.05 ||
+				math.Abs(interval.ToOffRoute - test.toOff) > 0.05 {
+			T.Fatalf("%s to %s: expected %.1f meters, %.1f and %.1f off route; " +
+				"got %.1f, %.1f, and %.1f", test.from, test.to, test.meters,
+				test.fromOff, test.toOff, interval.Meters, interval.FromOffRoute,
+				interval.ToOffRoute)
+		}
+	}
+	// Off-route distances are taken on the same model as distances along the route
+	err := vd.SetEarthModel("wgs84", nil)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	from, _ := ParseRoutePlace("path1End", 1)
+	to, _ := ParseRoutePlace("mission", 1)
+	interval, err := vd.MeasureBetween("theRoad", from, to)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if math.Abs(interval.ToOffRoute - 98.9) > 0.05 {
+		T.Fatalf("expected 98.9 meters off route on WGS 84, got %.1f", interval.ToOffRoute)
+	}
+	if err = vd.SetEarthModel("sphere", nil); err != nil {
+		T.Fatal(err.Error())
+	}
+	for _, test := range []struct{from, to, err string} {
+		{"pointStartPath1", "nowhere", "unknown map item 'nowhere'"},
+		{"pointStartPath1", "path2", "path 'path2' is not a waypoint"},
+		{"0", "2000", "route 'theRoad' is only 1235.9 meters (0.77 miles) long"},
+	} {
+		from, _ := ParseRoutePlace(test.from, 1)
+		to, _ := ParseRoutePlace(test.to, 1)
+		_, err := vd.MeasureBetween("theRoad", from, to)
+		if err == nil || err.Error() != test.err {
+			T.Fatalf("%s to %s: expected error '%s', got '%v'", test.from, test.to,
+				test.err, err)
+		}
+	}
+}
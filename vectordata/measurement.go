@@ -5,6 +5,7 @@ package vectordata
 
 import (
 	"fmt"
+	"math"
 
 	"potano.misiones/great"
 )
@@ -13,10 +14,15 @@ type measurementWalker interface {
 	measurePath(path *map_locationType, startOffset, endOffset locationIndexType) bool
 }
 
+// Walkers which need to know which component of a route or segment is being walked
+type componentNotingWalker interface {
+	beginComponent(parent *mapRouteOrSegmentType, child mapItemType)
+}
+
 
 
 func (vd *VectorData) MeasurePath(itemName string) (float64, error) {
-	measurer := &simplePathMeasurer{}
+	measurer := &simplePathMeasurer{earth: vd.earth}
 	err := vd.walkPathsForNamedItem(measurer, itemName, false)
 	if err != nil {
 		return 0, err
@@ -25,26 +31,40 @@ func (vd *VectorData) MeasurePath(itemName string) (float64, error) {
 }
 
 type simplePathMeasurer struct {
+	earth great.EarthModel
 	meters float64
 }
 
 func (spm *simplePathMeasurer) measurePath(path *map_locationType,
 		startOffset, endOffset locationIndexType) bool {
-	spm.meters += great.MetersInPath(path.location.asFloatSlice())
+	spm.meters += spm.earth.MetersInPath(path.location.asFloatSlice())
 	return true
 }
 
 
+// Finds the vertex nearest to the given distance along the item.  See MeasurePathToPosition for
+// the interpolated position.  Distances along a loop are taken from its start waypoint.
 func (vd *VectorData) MeasurePathUpTo(itemName string, upToDistance float64) (foundLat float64,
 		foundLong float64, distance float64, pathName string, index int, err error) {
-	measurer := &upToDistanceMeasurer{upToDistance: upToDistance, index: -1}
+	index = -1
+	start, total, isLoop, err := vd.measurementStart(itemName, nil)
+	if err != nil {
+		return
+	}
+	target, err := vd.distanceFromStart(itemName, start, total, upToDistance, isLoop)
+	if err != nil {
+		return
+	}
+	measurer := &upToDistanceMeasurer{earth: vd.earth, upToDistance: target, index: -1}
 	err = vd.walkPathsForNamedItem(measurer, itemName, false)
 	if err != nil {
-		index = -1
 		return
 	}
 	foundLat, foundLong = measurer.lat, measurer.long
 	distance, pathName, index = measurer.distance, measurer.pathName, measurer.index
+	if index >= 0 {
+		distance = distanceAroundLoop(distance, start, total, upToDistance, isLoop)
+	}
 	if index < 0 {
 		item := vd.mapItems[itemName]
 		err = fmt.Errorf("%s '%s' is only %.1f meters (%.2f miles) long",
@@ -55,6 +75,7 @@ func (vd *VectorData) MeasurePathUpTo(itemName string, upToDistance float64) (fo
 }
 
 type upToDistanceMeasurer struct {
+	earth great.EarthModel
 	lat, long, upToDistance, distance float64
 	pathName string
 	index int
@@ -69,7 +90,7 @@ func (updm *upToDistanceMeasurer) measurePath(path *map_locationType,
 	} else {
 		pairs = path.location.asFloatSlice()
 	}
-	steps := great.MetersBetweenPointPairs(pairs)
+	steps := updm.earth.MetersBetweenPointPairs(pairs)
 	distance := updm.distance
 	for index, step := range steps {
 		if distance + step >= updm.upToDistance {
@@ -99,6 +120,156 @@ func (updm *upToDistanceMeasurer) measurePath(path *map_locationType,
 
 
 
+// Position at a given distance along a path, route, or segment
+type pathPosition struct {
+	Lat, Long float64
+	Distance float64		// meters from the start of the item
+	Bearing float64			// degrees clockwise from north in the direction of travel
+	PathName string
+	FromIndex, ToIndex int		// vertices of the path on either side of the position
+}
+
+// Finds the position the given distance along the item, interpolating along the great-circle
+// step between the vertices on either side.  Distances along a loop are taken from its start
+// waypoint.
+func (vd *VectorData) MeasurePathToPosition(itemName string, upToDistance float64) (pathPosition,
+		error) {
+	return vd.MeasurePathToPositionFrom(itemName, nil, upToDistance)
+}
+
+// Finds the position the given distance along the item past the given place, or past the start
+// of the item or loop if the place is nil.  Past the closing point of a loop the distance
+// continues from its start.
+func (vd *VectorData) MeasurePathToPositionFrom(itemName string, from *RoutePlace,
+		upToDistance float64) (pathPosition, error) {
+	start, total, isLoop, err := vd.measurementStart(itemName, from)
+	if err != nil {
+		return pathPosition{}, err
+	}
+	target, err := vd.distanceFromStart(itemName, start, total, upToDistance, isLoop)
+	if err != nil {
+		return pathPosition{}, err
+	}
+	measurer := &interpolatingMeasurer{earth: vd.earth, upToDistance: target}
+	err = vd.walkPathsForNamedItem(measurer, itemName, false)
+	if err != nil {
+		return pathPosition{}, err
+	}
+	if !measurer.found {
+		item := vd.mapItems[itemName]
+		distance := measurer.position.Distance
+		if from != nil {
+			return pathPosition{}, fmt.Errorf("%s '%s' ends %.1f meters (%.2f miles) " +
+				"past %s", item.ItemTypeString(), item.Name(), distance - start,
+				(distance - start) / great.METERS_PER_MILE, *from)
+		}
+		return measurer.position, fmt.Errorf("%s '%s' is only %.1f meters (%.2f miles) long",
+			item.ItemTypeString(), item.Name(), distance,
+			distance / great.METERS_PER_MILE)
+	}
+	measurer.position.Distance = upToDistance
+	return measurer.position, nil
+}
+
+type interpolatingMeasurer struct {
+	earth great.EarthModel
+	upToDistance float64
+	position pathPosition
+	found bool
+}
+
+func (im *interpolatingMeasurer) measurePath(path *map_locationType,
+		startOffset, endOffset locationIndexType) bool {
+	reverse := endOffset < startOffset
+	var pairs []float64
+	if reverse {
+		pairs = path.location.asReverseFloatSlice()
+	} else {
+		pairs = path.location.asFloatSlice()
+	}
+	steps := im.earth.MetersBetweenPointPairs(pairs)
+	distance := im.position.Distance
+	for index, step := range steps {
+		if distance + step < im.upToDistance {
+			distance += step
+			continue
+		}
+		fraction := 0.0
+		if step > 0 {
+			fraction = (im.upToDistance - distance) / step
+		}
+		lat, long, bearing := positionInStep(pairs, index, fraction)
+		from, to := index, index + 1
+		if reverse {
+			from, to = len(steps) - from, len(steps) - to
+		}
+		im.position = pathPosition{
+			Lat: lat,
+			Long: long,
+			Distance: im.upToDistance,
+			Bearing: bearing,
+			PathName: path.Name(),
+			FromIndex: from,
+			ToIndex: to,
+		}
+		im.found = true
+		return false
+	}
+	im.position.Distance = distance
+	return true
+}
+
+
+// Interpolates the position the given fraction of the way along the step from vertex index to
+// vertex index+1 of a slice of latitude/longitude pairs.  Returns the latitude and longitude
+// in degrees and the bearing of travel in degrees clockwise from north.
+func positionInStep(pairs []float64, index int, fraction float64) (float64, float64, float64) {
+	lat1, long1 := pairs[index*2] * great.DEG_TO_RADIANS,
+		pairs[index*2 + 1] * great.DEG_TO_RADIANS
+	lat2, long2 := pairs[index*2 + 2] * great.DEG_TO_RADIANS,
+		pairs[index*2 + 3] * great.DEG_TO_RADIANS
+	lat, long := great.IntermediatePoint(lat1, long1, lat2, long2, fraction)
+	var bearing float64
+	if fraction < 1 {
+		bearing = great.InitialBearing(lat, long, lat2, long2)
+	} else {
+		bearing = great.InitialBearing(lat2, long2, lat1, long1) + math.Pi
+	}
+	bearing = math.Mod(bearing / great.DEG_TO_RADIANS + 360, 360)
+	return lat / great.DEG_TO_RADIANS, long / great.DEG_TO_RADIANS, bearing
+}
+
+
+
+
+// Collects the points of a threadable item in order of travel.  Paths which continue from the
+// end of the preceding path extend the current line; waypoints are gathered separately.
+type traversalCollector struct {
+	lines [][]latlongType
+	waypoints []*map_locationType
+}
+
+func (tc *traversalCollector) measurePath(path *map_locationType,
+		startOffset, endOffset locationIndexType) bool {
+	if path.isPoint() {
+		tc.waypoints = append(tc.waypoints, path)
+		return true
+	}
+	points := path.location.latlongPairs(endOffset < startOffset)
+	if numLines := len(tc.lines); numLines > 0 {
+		line := tc.lines[numLines - 1]
+		if line[len(line) - 1].samePoint(points[0]) {
+			tc.lines[numLines - 1] = append(line, points[1:]...)
+			return true
+		}
+	}
+	tc.lines = append(tc.lines, points)
+	return true
+}
+
+
+
+
 
 func (vd *VectorData) walkPathsForNamedItem(walker measurementWalker, name string,
 		reverse bool) error {
@@ -106,6 +277,10 @@ func (vd *VectorData) walkPathsForNamedItem(walker measurementWalker, name strin
 	if !exists {
 		return fmt.Errorf("unknown map item '%s'", name)
 	}
+	return walkPathsForItem(walker, item, reverse)
+}
+
+func walkPathsForItem(walker measurementWalker, item mapItemType, reverse bool) error {
 	var align, endpoint latlongType
 	var startOffset, endOffset locationIndexType
 	if tItem, is := item.(threadableMapItemType); !is {
@@ -136,6 +311,9 @@ func walkRouteOrSegment(walker measurementWalker, item *mapRouteOrSegmentType, a
 	}
 	for {
 		child := children[pos].(threadableMapItemType)
+		if noter, is := walker.(componentNotingWalker); is {
+			noter.beginComponent(item, child)
+		}
 		oppositeEndpoint, oppositeOffset, nearOffset := child.oppositeEndpoint(align)
 		switch tChild := child.(type) {
 		case *map_locationType:
@@ -0,0 +1,157 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"potano.misiones/great"
+	"potano.misiones/sexp"
+)
+
+
+type mapMilepostsType struct {
+	mapItemCore
+	interval float64
+	units string
+	style *mapStyleType
+	route *mapRouteOrSegmentType
+	markers []*map_locationType
+}
+
+func newMapMileposts(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	mm := &mapMilepostsType{route: parent.(*mapRouteOrSegmentType)}
+	mm.source = source
+	mm.name = parent.Name()
+	mm.itemType = mitMileposts
+	mm.route.mileposts = mm
+	doc.milepostRoutes = append(doc.milepostRoutes, mm)
+	return mm, nil
+}
+
+func (mm *mapMilepostsType) addScalars(targetName string, scalars []sexp.LispScalar) error {
+	switch targetName {
+	case "interval":
+		interval, err := strconv.ParseFloat(scalars[0].String(), 64)
+		if err != nil {
+			return mm.Error("%s", err)
+		}
+		if interval <= 0 {
+			return mm.Error("milepost interval must be greater than zero")
+		}
+		mm.interval = interval
+	case "units":
+		mm.units = scalars[0].String()
+	}
+	return nil
+}
+
+func (mm *mapMilepostsType) setStyle(style *mapStyleType) {
+	mm.style = style
+}
+
+
+// Synthesizes markers at each multiple of the milepost interval along the threaded routes
+// which call for them.  Each marker has a popup giving the distance from the start, which for
+// a loop is its start waypoint, as in measurement.
+func (vd *VectorData) placeMileposts() error {
+	for _, mm := range vd.milepostRoutes {
+		metersPerUnit, exists := vd.lengthUnits[mm.units]
+		if !exists {
+			return mm.Error("measurement unit '%s' is undefined", mm.units)
+		}
+		start, total, isLoop, err := vd.measurementStart(mm.route.Name(), nil)
+		if err != nil {
+			return mm.Error("%s", err)
+		}
+		placer := &milepostPlacer{earth: vd.earth, interval: mm.interval * metersPerUnit,
+			distance: -start}
+		err = walkPathsForItem(placer, mm.route, false)
+		if err == nil && start > 0 {
+			// Continue past the closing point of the loop back to the start waypoint
+			err = walkPathsForItem(placer, mm.route, false)
+		}
+		if err != nil {
+			return err
+		}
+		positions := placer.positions
+		if isLoop {
+			for len(positions) > 0 && float64(len(positions)) * placer.interval > total {
+				positions = positions[:len(positions) - 1]
+			}
+		}
+		for i, ll := range positions {
+			distance := float64(i + 1) * mm.interval
+			marker := &map_locationType{
+				vd: vd,
+				location: locationPairs{ll.lat, ll.long},
+				isPointType: true,
+				popup: &mapPopupType{text: milepostText(distance, mm.units)},
+				style: mm.style,
+			}
+			marker.source = mm.source
+			marker.itemType = mitMarker
+			marker.referrers = []string{mm.route.Name()}
+			name, err := vd.registerMapItem(marker,
+				fmt.Sprintf("$%s:milepost%d", mm.route.Name(), i + 1))
+			if err != nil {
+				return err
+			}
+			marker.name = name
+			mm.markers = append(mm.markers, marker)
+		}
+	}
+	return nil
+}
+
+
+func (mm *mapMilepostsType) featureList() []mapItemType {
+	list := make([]mapItemType, len(mm.markers))
+	for i, marker := range mm.markers {
+		list[i] = marker
+	}
+	return list
+}
+
+
+// Gives the distance in the unit exactly as named in the configuration
+func milepostText(distance float64, units string) string {
+	text := strconv.FormatFloat(math.Round(distance * 1000) / 1000, 'f', -1, 64)
+	return text + " " + units
+}
+
+
+// Finds the positions at each multiple of the interval along a threadable item
+type milepostPlacer struct {
+	earth great.EarthModel
+	interval, distance float64
+	positions []latlongType
+}
+
+func (mp *milepostPlacer) measurePath(path *map_locationType,
+		startOffset, endOffset locationIndexType) bool {
+	var pairs []float64
+	if endOffset < startOffset {
+		pairs = path.location.asReverseFloatSlice()
+	} else {
+		pairs = path.location.asFloatSlice()
+	}
+	steps := mp.earth.MetersBetweenPointPairs(pairs)
+	for index, step := range steps {
+		next := float64(len(mp.positions) + 1) * mp.interval
+		for step > 0 && mp.distance + step >= next {
+			lat, long, _ := positionInStep(pairs, index, (next - mp.distance) / step)
+			mp.positions = append(mp.positions, latlongType{
+				locAngleType(math.Round(lat / latLongFixedToFloatMultiplier)),
+				locAngleType(math.Round(long / latLongFixedToFloatMultiplier)),
+			})
+			next = float64(len(mp.positions) + 1) * mp.interval
+		}
+		mp.distance += step
+	}
+	return true
+}
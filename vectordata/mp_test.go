@@ -0,0 +1,108 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+
+const milepostRoute = `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(mileposts 1 furlongs (style mpStyle))
+		(segment
+			(paths path1 path2)
+		)
+	)
+	` + path1 + path2
+
+
+func Test_mileposts(T *testing.T) {
+	vd := prepareAndParseStrings(T, milepostRoute, `(config
+		(baseStyle mpStyle "color=#ff0000")
+		(lengthUnit furlongs 201.168 meters)
+	)`)
+	route := vd.mapItems["theRoad"].(*mapRouteOrSegmentType)
+	markers := route.mileposts.markers
+	if len(markers) != 6 {
+		T.Fatalf("expected 6 mileposts, got %d", len(markers))
+	}
+	for i, marker := range markers {
+		pos, err := vd.MeasurePathToPosition("theRoad", float64(i + 1) * 201.168)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		ll := marker.location.asFloatSlice()
+		if math.Abs(ll[0] - pos.Lat) > 5E-7 || math.Abs(ll[1] - pos.Long) > 5E-7 {
+			T.Fatalf("milepost %d: expected [%.6f %.6f], got [%.6f %.6f]", i + 1,
+				pos.Lat, pos.Long, ll[0], ll[1])
+		}
+		want := []string{"1 furlongs", "2 furlongs", "3 furlongs", "4 furlongs",
+			"5 furlongs", "6 furlongs"}[i]
+		if marker.popup.text != want {
+			T.Fatalf("milepost %d: expected popup '%s', got '%s'", i + 1, want,
+				marker.popup.text)
+		}
+	}
+
+	generated, err := vd.generateJson(FixedPointEncoding, nil)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	var doc map[string]any
+	err = json.Unmarshal([]byte(generated), &doc)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	features := doc["features"].([]any)
+	routeFeature := features[0].(map[string]any)
+	children := routeFeature["f"].([]any)
+	last := features[int(children[len(children) - 1].(float64))].(map[string]any)
+	checkAnyValue(T, last["t"], "last child type", "marker")
+	checkAnyValue(T, doc["texts"].([]any)[int(last["popup"].(float64))], "last popup",
+		"6 furlongs")
+	checkAnyValue(T, doc["styles"].([]any)[int(last["style"].(float64))], "milepost style",
+		map[string]any{"color": "#ff0000"})
+
+	geojson, err := vd.GenerateGeoJson()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if count := strings.Count(geojson, `"type":"marker"`); count != 6 {
+		T.Fatalf("expected 6 markers in GeoJSON output, got %d", count)
+	}
+}
+
+
+func Test_milepostErrors(T *testing.T) {
+	_, err := prepareAndParseStringsReturningError(T,
+		[]string{milepostRoute, `(config (baseStyle mpStyle "color=#ff0000"))`}, true)
+	if err == nil || err.Error() != "error reforming routes: infile0:8: " +
+			"measurement unit 'furlongs' is undefined" {
+		T.Fatalf("expected error for undefined unit, got %v", err)
+	}
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(`(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(mileposts 0 furlongs)
+		(segment
+			(paths path1 path2)
+		)
+	)
+	` + path1 + path2)},
+		"infile0:8: milepost interval must be greater than zero")
+}
@@ -0,0 +1,338 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"container/heap"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"potano.misiones/great"
+)
+
+// Treats every path of the dataset as part of a single network.  The nodes of the network are
+// the crosspoints found by the crossing finder plus the points of paths nearest places of
+// interest; the edges are the stretches of path between neighboring nodes.
+
+
+// Point of a path at which the network has a node:  the vertex at the offset or, for a
+// nonzero fraction, a point that fraction of the way to the next vertex
+type networkSplit struct {
+	offset locationIndexType
+	fraction float64
+	point latlongType
+}
+
+type networkEdge struct {
+	to int
+	meters float64
+	path *map_locationType
+	fromSplit, toSplit networkSplit
+}
+
+type pathNetwork struct {
+	nodes []latlongType
+	nodeIndex map[latlongType]int
+	edges [][]networkEdge
+}
+
+// Point of a path nearest a place of interest
+type networkAnchor struct {
+	path *map_locationType
+	at networkSplit
+	offNetwork float64
+}
+
+
+// Builds the network of the given paths with additional nodes at the given anchors
+func (vd *VectorData) buildPathNetwork(paths []*map_locationType,
+		anchors []networkAnchor) *pathNetwork {
+	pn := &pathNetwork{nodeIndex: map[latlongType]int{}}
+	extraSplits := map[*map_locationType][]networkSplit{}
+	for _, anchor := range anchors {
+		extraSplits[anchor.path] = append(extraSplits[anchor.path], anchor.at)
+	}
+	for _, path := range paths {
+		splits := extraSplits[path]
+		for _, ref := range path.crossings {
+			splits = append(splits, path.splitAtVertex(ref.indices[0]))
+		}
+		_, _, first, last := path.endpointsAndOffsets()
+		splits = append(splits, path.splitAtVertex(first), path.splitAtVertex(last))
+		sort.Slice(splits, func (i, j int) bool { return splits[i].before(splits[j]) })
+		prev := splits[0]
+		for _, split := range splits[1:] {
+			if !prev.before(split) {
+				continue
+			}
+			stretch := path.pointsBetween(prev, split)
+			meters := vd.earth.MetersInPath(locationPairs(stretch).asFloatSlice())
+			pn.addEdge(path, prev, split, meters)
+			prev = split
+		}
+	}
+	return pn
+}
+
+
+func (ml *map_locationType) splitAtVertex(offset locationIndexType) networkSplit {
+	return networkSplit{offset: offset, point: ml.pointAtOffset(offset)}
+}
+
+func (ns networkSplit) before(other networkSplit) bool {
+	return ns.offset < other.offset ||
+		(ns.offset == other.offset && ns.fraction < other.fraction)
+}
+
+// Lists the coordinates of the stretch of the path from one split to a later one
+func (ml *map_locationType) pointsBetween(from, to networkSplit) []locAngleType {
+	points := []locAngleType{from.point.lat, from.point.long}
+	for offset := from.offset + 2; offset <= to.offset; offset += 2 {
+		points = append(points, ml.location[offset], ml.location[offset + 1])
+	}
+	if to.fraction > 0 {
+		points = append(points, to.point.lat, to.point.long)
+	}
+	return points
+}
+
+
+// Paths as declared in the dataset, excluding the parts split from them when threading
+func (vd *VectorData) networkPaths() []*map_locationType {
+	var paths []*map_locationType
+	for _, item := range vd.mapItems {
+		if path, is := item.(*map_locationType); is && path.ItemType() == mitPath &&
+				path.prototypePath == nil && len(path.location) >= 4 {
+			paths = append(paths, path)
+		}
+	}
+	// Make the choice among equally short routes repeatable
+	sort.Slice(paths, func (i, j int) bool { return paths[i].locIndex < paths[j].locIndex })
+	return paths
+}
+
+
+func (pn *pathNetwork) node(point latlongType) int {
+	index, exists := pn.nodeIndex[point]
+	if !exists {
+		index = len(pn.nodes)
+		pn.nodeIndex[point] = index
+		pn.nodes = append(pn.nodes, point)
+		pn.edges = append(pn.edges, nil)
+	}
+	return index
+}
+
+func (pn *pathNetwork) addEdge(path *map_locationType, fromSplit, toSplit networkSplit,
+		meters float64) {
+	from := pn.node(fromSplit.point)
+	to := pn.node(toSplit.point)
+	pn.edges[from] = append(pn.edges[from], networkEdge{to, meters, path, fromSplit, toSplit})
+	pn.edges[to] = append(pn.edges[to], networkEdge{from, meters, path, toSplit, fromSplit})
+}
+
+
+// Finds the shortest distances from the start node to every node.  Unreachable nodes have
+// infinite distances.  The returned edges are those by which the shortest routes arrive at
+// each node.
+func (pn *pathNetwork) shortestFrom(start int) ([]float64, []*networkEdge) {
+	distances := make([]float64, len(pn.nodes))
+	arrivals := make([]*networkEdge, len(pn.nodes))
+	for i := range distances {
+		distances[i] = math.Inf(1)
+	}
+	distances[start] = 0
+	queue := &nodeQueue{{start, 0}}
+	for queue.Len() > 0 {
+		next := heap.Pop(queue).(queuedNode)
+		if next.meters > distances[next.node] {
+			continue
+		}
+		for i, edge := range pn.edges[next.node] {
+			meters := next.meters + edge.meters
+			if meters < distances[edge.to] {
+				distances[edge.to] = meters
+				arrivals[edge.to] = &pn.edges[next.node][i]
+				heap.Push(queue, queuedNode{edge.to, meters})
+			}
+		}
+	}
+	return distances, arrivals
+}
+
+
+type queuedNode struct {
+	node int
+	meters float64
+}
+
+type nodeQueue []queuedNode
+
+func (nq nodeQueue) Len() int { return len(nq) }
+func (nq nodeQueue) Less(i, j int) bool { return nq[i].meters < nq[j].meters }
+func (nq nodeQueue) Swap(i, j int) { nq[i], nq[j] = nq[j], nq[i] }
+func (nq *nodeQueue) Push(x any) { *nq = append(*nq, x.(queuedNode)) }
+func (nq *nodeQueue) Pop() any {
+	old := *nq
+	last := old[len(old) - 1]
+	*nq = old[:len(old) - 1]
+	return last
+}
+
+
+// Locates the point of a path nearest a named point, marker, or circle
+func (vd *VectorData) anchorPlace(name string, paths []*map_locationType) (networkAnchor, error) {
+	item, exists := vd.mapItems[name]
+	if !exists {
+		return networkAnchor{}, fmt.Errorf("unknown map item '%s'", name)
+	}
+	loc, is := item.(*map_locationType)
+	if !is || !loc.isPoint() {
+		return networkAnchor{}, fmt.Errorf("%s '%s' is not a point, marker, or circle",
+			item.ItemTypeString(), name)
+	}
+	if len(paths) == 0 {
+		return networkAnchor{}, fmt.Errorf("the dataset has no paths")
+	}
+	place := loc.location.asFloatSlice()
+	lat, long := place[0] * great.DEG_TO_RADIANS, place[1] * great.DEG_TO_RADIANS
+	best := networkAnchor{offNetwork: math.Inf(1)}
+	var bestFraction float64
+	for _, path := range paths {
+		pairs := path.location.asFloatSlice()
+		for i := range pairs {
+			pairs[i] *= great.DEG_TO_RADIANS
+		}
+		for i := 0; i + 3 < len(pairs); i += 2 {
+			meters, fraction := vd.earth.NearestOnSegment(lat, long, pairs[i], pairs[i+1],
+				pairs[i+2], pairs[i+3])
+			if meters < best.offNetwork {
+				best.path, best.at.offset, best.offNetwork = path, locationIndexType(i), meters
+				bestFraction = fraction
+			}
+		}
+	}
+	// Split the edge at the nearest point unless that is one of its vertices
+	path, offset := best.path, best.at.offset
+	pairs := path.location.asFloatSlice()
+	pointLat, pointLong := great.IntermediatePoint(pairs[offset] * great.DEG_TO_RADIANS,
+		pairs[offset + 1] * great.DEG_TO_RADIANS, pairs[offset + 2] * great.DEG_TO_RADIANS,
+		pairs[offset + 3] * great.DEG_TO_RADIANS, bestFraction)
+	toFixed := great.DEG_TO_RADIANS * latLongFixedToFloatMultiplier
+	point := latlongType{locAngleType(math.Round(pointLat / toFixed)),
+		locAngleType(math.Round(pointLong / toFixed))}
+	switch {
+	case point.samePoint(path.pointAtOffset(offset)):
+		best.at = path.splitAtVertex(offset)
+	case point.samePoint(path.pointAtOffset(offset + 2)):
+		best.at = path.splitAtVertex(offset + 2)
+	default:
+		best.at = networkSplit{offset, bestFraction, point}
+	}
+	return best, nil
+}
+
+
+
+type distanceMatrix struct {
+	Places []string
+	Meters [][]float64		// NaN where no connection exists
+	OffNetwork []float64		// distance from each place to the nearest path
+}
+
+// Computes the shortest distances along the network of all paths between each pair of the
+// named points, markers, or circles.  Places off the network are connected to the nearest
+// point of a path.
+func (vd *VectorData) MeasureDistanceMatrix(places []string) (distanceMatrix, error) {
+	matrix := distanceMatrix{Places: places}
+	paths := vd.networkPaths()
+	anchors := make([]networkAnchor, len(places))
+	for i, name := range places {
+		anchor, err := vd.anchorPlace(name, paths)
+		if err != nil {
+			return matrix, err
+		}
+		anchors[i] = anchor
+		matrix.OffNetwork = append(matrix.OffNetwork, anchor.offNetwork)
+	}
+	pn := vd.buildPathNetwork(paths, anchors)
+	nodes := make([]int, len(anchors))
+	for i, anchor := range anchors {
+		nodes[i] = pn.nodeIndex[anchor.at.point]
+	}
+	matrix.Meters = make([][]float64, len(places))
+	for i, from := range nodes {
+		distances, _ := pn.shortestFrom(from)
+		row := make([]float64, len(places))
+		for j, to := range nodes {
+			row[j] = distances[to]
+			if math.IsInf(row[j], 1) {
+				row[j] = math.NaN()
+			}
+		}
+		matrix.Meters[i] = row
+	}
+	return matrix, nil
+}
+
+
+// Formats the matrix as CSV with a header row of place names.  Missing connections are empty.
+func (dm distanceMatrix) CSV(metersPerUnit float64) (string, error) {
+	var sb strings.Builder
+	writer := csv.NewWriter(&sb)
+	err := writer.Write(append([]string{""}, dm.Places...))
+	for i, row := range dm.Meters {
+		if err != nil {
+			break
+		}
+		record := []string{dm.Places[i]}
+		for _, meters := range row {
+			cell := ""
+			if !math.IsNaN(meters) {
+				cell = strconv.FormatFloat(meters / metersPerUnit, 'f', 2, 64)
+			}
+			record = append(record, cell)
+		}
+		err = writer.Write(record)
+	}
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	return sb.String(), err
+}
+
+
+// Formats the matrix as JSON.  Missing connections are null.
+func (dm distanceMatrix) JSON(metersPerUnit float64, unitName string) (string, error) {
+	out := struct {
+		Units string `json:"units"`
+		Places []string `json:"places"`
+		Distances [][]*float64 `json:"distances"`
+		OffNetwork []float64 `json:"offNetwork"`
+	}{Units: unitName, Places: dm.Places}
+	round := func (meters float64) float64 {
+		return math.Round(meters / metersPerUnit * 100) / 100
+	}
+	for _, row := range dm.Meters {
+		outRow := make([]*float64, len(row))
+		for j, meters := range row {
+			if !math.IsNaN(meters) {
+				value := round(meters)
+				outRow[j] = &value
+			}
+		}
+		out.Distances = append(out.Distances, outRow)
+	}
+	for _, meters := range dm.OffNetwork {
+		out.OffNetwork = append(out.OffNetwork, round(meters))
+	}
+	blob, err := json.MarshalIndent(out, "", "  ")
+	return string(blob) + "\n", err
+}
@@ -0,0 +1,128 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+
+func Test_distanceMatrix(T *testing.T) {
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features ab bc adc xy pa pc pd pm pn px)
+		)
+	)
+	(path ab 30.0 -83.0 30.0 -83.02)
+	(path bc 30.0 -83.02 30.01 -83.01)
+	(path adc 30.0 -83.0 30.01 -83.0 30.01 -83.01)
+	(path xy 30.5 -83.5 30.6 -83.6)
+	(point pa 30.0 -83.0)
+	(point pc 30.01 -83.01)
+	(marker pd 30.01 -83.0)
+	(marker pm 29.9997 -83.0203)
+	(marker pn 30.0005 -83.01)
+	(point px 30.6 -83.6)
+	`)
+	lengthOf := func (name string) float64 {
+		meters, err := vd.MeasurePath(name)
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		return meters
+	}
+	ab, bc, adc := lengthOf("ab"), lengthOf("bc"), lengthOf("adc")
+	ad := vd.earth.MetersInPath([]float64{30.0, -83.0, 30.01, -83.0})
+	ahalf := vd.earth.MetersInPath([]float64{30.0, -83.0, 30.0, -83.01})
+
+	matrix, err := vd.MeasureDistanceMatrix([]string{"pa", "pc", "pd", "pm", "px", "pn"})
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	for i := range matrix.Places {
+		if matrix.Meters[i][i] != 0 {
+			T.Fatalf("distance from %s to itself is %f", matrix.Places[i], matrix.Meters[i][i])
+		}
+		for j := range matrix.Places {
+			a, b := matrix.Meters[i][j], matrix.Meters[j][i]
+			if a != b && !(math.IsNaN(a) && math.IsNaN(b)) {
+				T.Fatalf("asymmetric distances between %s and %s: %f, %f",
+					matrix.Places[i], matrix.Places[j], a, b)
+			}
+		}
+	}
+	compareTestLengths(T, "pa-pc", matrix.Meters[0][1], math.Min(ab + bc, adc))
+	compareTestLengths(T, "pa-pd", matrix.Meters[0][2], ad)
+	compareTestLengths(T, "pd-pc", matrix.Meters[2][1], adc - ad)
+	compareTestLengths(T, "pm-pa", matrix.Meters[3][0], ab)
+	compareTestLengths(T, "pm-pc", matrix.Meters[3][1], bc)
+	if !math.IsNaN(matrix.Meters[0][4]) {
+		T.Fatalf("expected no connection from pa to px, got %f", matrix.Meters[0][4])
+	}
+	if matrix.OffNetwork[0] != 0 || matrix.OffNetwork[2] != 0 {
+		T.Fatalf("expected pa and pd on the network, got %f and %f", matrix.OffNetwork[0],
+			matrix.OffNetwork[2])
+	}
+	compareTestLengths(T, "pm off network", matrix.OffNetwork[3],
+		vd.earth.MetersInPath([]float64{29.9997, -83.0203, 30.0, -83.02}))
+	// pn is abeam the middle of ab, which is split there
+	compareTestLengths(T, "pn-pa", matrix.Meters[5][0], ahalf)
+	compareTestLengths(T, "pn-pm", matrix.Meters[5][3], ab - ahalf)
+	compareTestLengths(T, "pn off network", matrix.OffNetwork[5],
+		vd.earth.MetersInPath([]float64{30.0005, -83.01, 30.0, -83.01}))
+
+	_, err = vd.MeasureDistanceMatrix([]string{"pa", "ab"})
+	if err == nil || err.Error() != "path 'ab' is not a point, marker, or circle" {
+		T.Fatalf("expected error for path, got %v", err)
+	}
+	_, err = vd.MeasureDistanceMatrix([]string{"pa", "nowhere"})
+	if err == nil || err.Error() != "unknown map item 'nowhere'" {
+		T.Fatalf("expected error for unknown item, got %v", err)
+	}
+}
+
+
+func Test_distanceMatrixFormats(T *testing.T) {
+	matrix := distanceMatrix{
+		Places: []string{"a", "b", "c"},
+		Meters: [][]float64{
+			{0, 1609.344, math.NaN()},
+			{1609.344, 0, math.NaN()},
+			{math.NaN(), math.NaN(), 0},
+		},
+		OffNetwork: []float64{0, 0, 16.09344},
+	}
+	text, err := matrix.CSV(1609.344)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	expected := strings.Join([]string{",a,b,c", "a,0.00,1.00,", "b,1.00,0.00,", "c,,,0.00", ""},
+		"\n")
+	if text != expected {
+		T.Fatalf("expected CSV\n%s\ngot\n%s", expected, text)
+	}
+
+	text, err = matrix.JSON(1609.344, "miles")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	var decoded struct {
+		Units string
+		Places []string
+		Distances [][]*float64
+		OffNetwork []float64
+	}
+	err = json.Unmarshal([]byte(text), &decoded)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if decoded.Units != "miles" || len(decoded.Places) != 3 || *decoded.Distances[0][1] != 1 ||
+			decoded.Distances[0][2] != nil || decoded.OffNetwork[2] != 0.01 {
+		T.Fatalf("unexpected JSON output %s", text)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+
+const periodData = `(layers
+		(layer one
+			(menuitem "Look")
+			(features sanLuis sanPedro camino)
+		)
+	)
+	(marker sanLuis (period 1656 1704) 30.0 -83.0)
+	(marker sanPedro (period 1633) 30.1 -83.1)
+	(route camino
+		(period 1633 1704)
+		(segment
+			(path early (period 1633 1680) 30.0 -83.0 30.05 -83.05)
+			(path late 30.05 -83.05 30.1 -83.1)
+		)
+	)
+	`
+
+
+// Returns the names of the layer's features in the generated data along with the features
+func generatedFeatures(T *testing.T, vd *VectorData) ([]map[string]any, map[string]any) {
+	T.Helper()
+	generated, err := vd.generateJson(FixedPointEncoding, nil)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	var doc map[string]any
+	err = json.Unmarshal([]byte(generated), &doc)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	features := doc["features"].([]any)
+	menuitem := doc["menuitems"].([]any)[0].(map[string]any)
+	var listed []map[string]any
+	for _, index := range menuitem["f"].([]any) {
+		listed = append(listed, features[int(index.(float64))].(map[string]any))
+	}
+	return listed, doc
+}
+
+
+func Test_period(T *testing.T) {
+	vd := prepareAndParseStrings(T, periodData, `(config (baseStyle plain "color=#000000"))`)
+	listed, doc := generatedFeatures(T, vd)
+	if len(listed) != 3 {
+		T.Fatalf("expected 3 features, got %d", len(listed))
+	}
+	checkAnyValue(T, listed[0]["from"], "sanLuis from", 1656.0)
+	checkAnyValue(T, listed[0]["to"], "sanLuis to", 1704.0)
+	checkAnyValue(T, listed[1]["from"], "sanPedro from", 1633.0)
+	if _, exists := listed[1]["to"]; exists {
+		T.Fatalf("open-ended period has an end")
+	}
+	features := doc["features"].([]any)
+	segment := features[int(listed[2]["f"].([]any)[0].(float64))].(map[string]any)
+	if len(segment["f"].([]any)) != 2 {
+		T.Fatalf("expected 2 paths in the segment, got %d", len(segment["f"].([]any)))
+	}
+
+	err := vd.KeepActiveBetween(1640, 1650)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	listed, doc = generatedFeatures(T, vd)
+	if len(listed) != 2 || listed[0]["from"] != 1633.0 || listed[1]["t"] != "route" {
+		T.Fatalf("expected sanPedro and camino in 1640-1650, got %v", listed)
+	}
+	features = doc["features"].([]any)
+	segment = features[int(listed[1]["f"].([]any)[0].(float64))].(map[string]any)
+	if len(segment["f"].([]any)) != 2 {
+		T.Fatalf("expected 2 paths in the segment, got %d", len(segment["f"].([]any)))
+	}
+
+	err = vd.KeepActiveBetween(1690, 1690)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	listed, doc = generatedFeatures(T, vd)
+	if len(listed) != 3 {
+		T.Fatalf("expected 3 features in 1690, got %d", len(listed))
+	}
+	features = doc["features"].([]any)
+	segment = features[int(listed[2]["f"].([]any)[0].(float64))].(map[string]any)
+	if len(segment["f"].([]any)) != 1 {
+		T.Fatalf("expected only the late path in 1690, got %d", len(segment["f"].([]any)))
+	}
+
+	err = vd.KeepActiveBetween(1710, 1800)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	geojson, err := vd.GenerateGeoJson()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if strings.Count(geojson, `"type":"Feature"`) != 1 ||
+			!strings.Contains(geojson, `"from":1633,"layers":["Look"],"name":"sanPedro"`) {
+		T.Fatalf("expected only sanPedro after 1704, got %s", geojson)
+	}
+
+	err = vd.KeepActiveBetween(1704, 1633)
+	if err == nil || err.Error() != "year 1633 comes before year 1704" {
+		T.Fatalf("expected error for years out of order, got %v", err)
+	}
+}
+
+
+func Test_periodErrors(T *testing.T) {
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(`(layers
+		(layer one
+			(menuitem "Look")
+			(features sanLuis)
+		)
+	)
+	(marker sanLuis (period 1704 1656) 30.0 -83.0)
+	`)},
+		"infile0:7: period ends in 1656 before it begins in 1704")
+}
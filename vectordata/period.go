@@ -0,0 +1,104 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"strconv"
+
+	"potano.misiones/sexp"
+)
+
+// The (period start [end]) attribute gives the years in which a map item existed, as from the
+// founding to the abandonment of a mission.  A period without an end is open-ended.  The years
+// are written into the generated data, and output may be restricted to the items active in a
+// window of years.  Items without a period are always active; since an item is written only
+// within the items which contain it, they also take on the period of those.
+
+
+type mapPeriodType struct {
+	mapItemCore
+	from, to int
+	hasEnd bool
+}
+
+func newMapPeriod(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	mp := &mapPeriodType{}
+	mp.source = source
+	mp.name = parent.Name()
+	mp.itemType = mitPeriod
+	return mp, nil
+}
+
+func (mp *mapPeriodType) addScalars(targetName string, scalars []sexp.LispScalar) error {
+	switch targetName {
+	case "years":
+		years := make([]int, len(scalars))
+		for i, scalar := range scalars {
+			year, err := strconv.Atoi(scalar.String())
+			if err != nil {
+				return mp.Error("%s", err)
+			}
+			years[i] = year
+		}
+		mp.from = years[0]
+		if len(years) > 1 {
+			if years[1] < years[0] {
+				return mp.Error("period ends in %d before it begins in %d", years[1],
+					years[0])
+			}
+			mp.to, mp.hasEnd = years[1], true
+		}
+	}
+	return nil
+}
+
+func (mp *mapPeriodType) overlaps(other *mapPeriodType) bool {
+	return (!other.hasEnd || mp.from <= other.to) && (!mp.hasEnd || mp.to >= other.from)
+}
+
+// Returns the start and end years to write into the generated data; zero for none
+func (mp *mapPeriodType) fromAndTo() (nonZeroInt, nonZeroInt) {
+	if mp == nil {
+		return 0, 0
+	}
+	if !mp.hasEnd {
+		return nonZeroInt(mp.from), 0
+	}
+	return nonZeroInt(mp.from), nonZeroInt(mp.to)
+}
+
+
+func periodOf(item mapItemType) *mapPeriodType {
+	switch item := item.(type) {
+	case *mapFeatureType:
+		return item.period
+	case *mapRouteOrSegmentType:
+		return item.period
+	case *map_locationType:
+		if item.prototypePath != nil {
+			return item.prototypePath.period
+		}
+		return item.period
+	case *threadableMapItemReference:
+		return periodOf(item.item)
+	}
+	return nil
+}
+
+
+// Restricts the generated output to the items active at some time in the given years
+func (vd *VectorData) KeepActiveBetween(fromYear, toYear int) error {
+	if toYear < fromYear {
+		return fmt.Errorf("year %d comes before year %d", toYear, fromYear)
+	}
+	vd.activeYears = &mapPeriodType{from: fromYear, to: toYear, hasEnd: true}
+	return nil
+}
+
+func (vd *VectorData) isActive(item mapItemType) bool {
+	period := periodOf(item)
+	return vd.activeYears == nil || period == nil || period.overlaps(vd.activeYears)
+}
@@ -15,9 +15,15 @@ type mapRouteOrSegmentType struct {
 	popup *mapPopupType
 	style *mapStyleType
 	attestation *mapAttestationType
+	period *mapPeriodType
 	children []mapItemType
 	startPoint, endPoint latlongType
 	crossings latlongRefs
+	mileposts *mapMilepostsType
+	travel *mapTravelRateType
+	loop *mapLoopType
+	alternatives []*mapAlternativesType
+	alternative *alternativeRef
 }
 
 func newMapRoute(doc *VectorData, parent mapItemType, listType, listName string,
@@ -45,6 +51,8 @@ func (mr *mapRouteOrSegmentType) clone(vd *VectorData, parent mapItemType) *mapR
 		popup: mr.popup,
 		style: mr.style,
 		attestation: mr.attestation,
+		period: mr.period,
+		alternative: mr.alternative,
 	}
 	newMR.source = mr.source
 	newMR.name = registerSplitName(vd, newMR, mr.Name())
@@ -65,7 +73,17 @@ func (mr *mapRouteOrSegmentType) setAttestation(attestation *mapAttestationType)
 	mr.attestation = attestation
 }
 
+func (mr *mapRouteOrSegmentType) setPeriod(period *mapPeriodType) {
+	mr.period = period
+}
+
 func (mr *mapRouteOrSegmentType) addFeature(feature mapItemType) {
+	if group, is := feature.(*mapAlternativesType); is {
+		// The default alternative stands in the chain of components
+		mr.alternatives = append(mr.alternatives, group)
+		group.number = len(mr.alternatives)
+		feature = group.members[0]
+	}
 	mr.children = append(mr.children, feature)
 }
 
@@ -0,0 +1,118 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"strings"
+	"testing"
+)
+
+
+// Paths a and b meet at both ends of path a
+const loopRoute = `(layers
+		(layer one
+			(menuitem "Look")
+			(features road)
+		)
+	)
+	(route road
+		(segment
+			(paths a b)
+		)
+	)
+	(path a 30.0 -83.0 30.0 -83.01)
+	(path b 30.0 -83.01 30.01 -83.01 30.0 -83.0)
+	`
+
+// Both ends of path one are free
+const midPathWaypoint = `(layers
+		(layer ll
+			(menuitem "here")
+			(features road)
+		)
+	)
+	(feature road
+		(segment test
+			(point wp 2.1 2.2)
+			(path one
+				1.1 1.2
+				2.1 2.2
+				3.1 3.2
+				4.1 4.2
+			)
+		)
+	)
+	`
+
+
+func Test_threadingSuggestions(T *testing.T) {
+	for _, test := range []struct {
+		source, suggestion, fixed string
+	}{
+		{loopRoute, "infile0:9: add (point 30.000000 -83.010000) between path 'a' and " +
+			"path 'b' in anonymous segment to join them there; (point 30.000000 -83.000000) " +
+			"would join them elsewhere",
+			"\t\t\t(paths a) (point 30.000000 -83.010000) (paths b)\n"},
+		{midPathWaypoint, "infile0:10: add (point 4.100000 4.200000) after path 'one' in " +
+			"segment 'test' to end it there; (point 1.100000 1.200000) would end it at its " +
+			"other end",
+			"\t\t\t\t4.1 4.2\n\t\t\t)\n\t\t\t(point 4.100000 4.200000)\n\t\t)\n"},
+	} {
+		vd := prepareAndParseStringsIgnoreThreadingError(T, test.source)
+		suggestions := vd.ThreadingSuggestions()
+		if len(suggestions) != 1 || suggestions[0].String() != test.suggestion {
+			T.Fatalf("expected suggestion '%s', got %v", test.suggestion, suggestions)
+		}
+		files, unfixed, err := vd.FixThreading(func (fileName string) (string, error) {
+			return test.source, nil
+		})
+		if err != nil {
+			T.Fatal(err.Error())
+		}
+		if len(files) != 1 || len(unfixed) != 0 || files[0].FileName != "infile0" {
+			T.Fatalf("expected one fixed file, got %v and unfixed %v", files, unfixed)
+		}
+		if !strings.Contains(files[0].Blob, test.fixed) {
+			T.Fatalf("expected\n%s\nin\n%s", test.fixed, files[0].Blob)
+		}
+
+		// The fixed source threads without ambiguity
+		vd = prepareAndParseStrings(T, files[0].Blob)
+		if len(vd.ThreadingSuggestions()) != 0 {
+			T.Fatalf("expected no suggestions, got %v", vd.ThreadingSuggestions())
+		}
+	}
+}
+
+
+func Test_findReferenceEdit(T *testing.T) {
+	for _, test := range []struct {
+		text, name string
+		before bool
+		expected string
+	}{
+		{"(segment (paths a b c))", "a", true, "(segment (point 1 2) (paths a b c))"},
+		{"(paths a b c)", "b", true, "(paths a) (point 1 2) (paths b c)"},
+		{"(paths a b c)", "b", false, "(paths a b) (point 1 2) (paths c)"},
+		{"(segment (paths a b c))", "c", false, "(segment (paths a b c) (point 1 2))"},
+		{"\t(paths a\n\t\tb)\n", "b", false, "\t(paths a\n\t\tb)\n\t(point 1 2)\n"},
+		{"(paths a b a)", "a", true, ""},
+		{"(paths ab b)", "b", true, "(paths ab) (point 1 2) (paths b)"},
+	} {
+		lineno := strings.Count(test.text[:strings.LastIndex(test.text, test.name)], "\n") + 1
+		edit, found := findReferenceEdit(test.text, lineno, test.name, "(point 1 2)",
+			test.before)
+		if found != (len(test.expected) > 0) {
+			T.Fatalf("%s %s: expected found %v", test.text, test.name, !found)
+		}
+		if !found {
+			continue
+		}
+		got := applySourceEdits(test.text, []sourceEdit{edit})
+		if got != test.expected {
+			T.Fatalf("%s %s: expected '%s', got '%s'", test.text, test.name, test.expected,
+				got)
+		}
+	}
+}
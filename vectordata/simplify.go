@@ -0,0 +1,90 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"potano.misiones/great"
+)
+
+// Douglas-Peucker simplification of paths and polygons for display at reduced zoom levels.
+// Simplified vertices are used only for display; measurement and threading always use the
+// full-resolution locations.
+
+
+// Returns the indices of the vertices of the location to keep in order for no discarded vertex
+// to lie farther than the tolerance (in meters) from the simplified line.  The endpoints and
+// the vertices at crosspoints, which include the locations of route waypoints, are always
+// kept, as is the vertex of a polygon which is farthest from the first.  Distances are taken on
+// the given earth model.
+func simplifiedVertices(earth great.EarthModel, loc *map_locationType, tolerance float64) []int {
+	numVertices := len(loc.location) >> 1
+	if numVertices < 3 {
+		kept := make([]int, numVertices)
+		for i := range kept {
+			kept[i] = i
+		}
+		return kept
+	}
+	keep := make([]bool, numVertices)
+	keep[0] = true
+	keep[numVertices - 1] = true
+	for _, ref := range loc.crossings {
+		if vertex := int(ref.indices[0]) >> 1; vertex < numVertices {
+			keep[vertex] = true
+		}
+	}
+	coords := loc.location.asFloatSlice()
+	for i := range coords {
+		coords[i] *= great.DEG_TO_RADIANS
+	}
+	if loc.itemType == mitPolygon {
+		// Keep the polygon from collapsing into a line
+		farthest, maxMeters := 0, 0.0
+		for i := 1; i < numVertices - 1; i++ {
+			meters := earth.MetersBetweenPoints(coords[0], coords[1], coords[i*2],
+				coords[i*2 + 1])
+			if meters > maxMeters {
+				farthest, maxMeters = i, meters
+			}
+		}
+		keep[farthest] = true
+	}
+	start := 0
+	for end := 1; end < numVertices; end++ {
+		if keep[end] {
+			markDouglasPeucker(earth, coords, keep, start, end, tolerance)
+			start = end
+		}
+	}
+	kept := make([]int, 0, numVertices)
+	for i, k := range keep {
+		if k {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+
+func markDouglasPeucker(earth great.EarthModel, coords []float64, keep []bool, start, end int,
+		tolerance float64) {
+	spans := [][2]int{{start, end}}
+	for len(spans) > 0 {
+		span := spans[len(spans) - 1]
+		spans = spans[:len(spans) - 1]
+		first, last := span[0], span[1]
+		farthest, maxMeters := -1, tolerance
+		for i := first + 1; i < last; i++ {
+			meters := earth.MetersFromSegment(coords[i*2], coords[i*2 + 1],
+				coords[first*2], coords[first*2 + 1], coords[last*2], coords[last*2 + 1])
+			if meters > maxMeters {
+				farthest, maxMeters = i, meters
+			}
+		}
+		if farthest >= 0 {
+			keep[farthest] = true
+			spans = append(spans, [2]int{first, farthest}, [2]int{farthest, last})
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+
+// The second path misses the end of the first by one microdegree of latitude
+const nearMissRoute = `(layers
+		(layer one
+			(menuitem "Look")
+			(features road)
+		)
+	)
+	(route road
+		(segment
+			(paths first second)
+		)
+	)
+	(path first 30.0 -83.0 30.0 -83.01)
+	(path second 30.000001 -83.01 30.01 -83.01)
+	`
+
+
+func Test_snapToleranceReport(T *testing.T) {
+	vd := prepareAndParseStringsIgnoreThreadingError(T, nearMissRoute)
+	if len(vd.DeferredErrors()) == 0 {
+		T.Fatalf("expected threading to fail without snapping")
+	}
+	if len(vd.NearCoincidences()) != 0 {
+		T.Fatalf("expected no near coincidences without a snap tolerance")
+	}
+
+	vd = prepareAndParseStringsIgnoreThreadingError(T, nearMissRoute,
+		"(config (snapTolerance 0.5))")
+	near := vd.NearCoincidences()
+	if len(near) != 1 {
+		T.Fatalf("expected 1 near coincidence, got %d", len(near))
+	}
+	expected := "path 'first' point 2 (30.000000,-83.010000) and " +
+		"path 'second' point 1 (30.000001,-83.010000) are 0.11 meters apart"
+	if near[0].String() != expected {
+		T.Fatalf("expected '%s', got '%s'", expected, near[0].String())
+	}
+
+	vd = prepareAndParseStringsIgnoreThreadingError(T, nearMissRoute,
+		"(config (snapTolerance 0.1))")
+	if len(vd.NearCoincidences()) != 0 {
+		T.Fatalf("expected no near coincidences within 0.1 meters")
+	}
+}
+
+
+func Test_snapToleranceMerge(T *testing.T) {
+	vd := prepareAndParseStrings(T, nearMissRoute, "(config (snapTolerance 0.5 merge))")
+	near := vd.NearCoincidences()
+	if len(near) != 1 || !near[0].Merged {
+		T.Fatalf("expected 1 merged point, got %v", near)
+	}
+	first := vd.mapItems["first"].(*map_locationType).location
+	second := vd.mapItems["second"].(*map_locationType).location
+	if !first.latlongPair(2).samePoint(second.latlongPair(0)) {
+		T.Fatalf("expected paths to share a point, got %v and %v", first.latlongPair(2),
+			second.latlongPair(0))
+	}
+	meters, err := vd.MeasurePath("road")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "road", meters, vd.earth.MetersInPath(first.asFloatSlice()) +
+		vd.earth.MetersInPath(second.asFloatSlice()))
+}
+
+
+func Test_snapToleranceMergeSameLocation(T *testing.T) {
+	// The second and third points of path p are nearly coincident, and the second is shared
+	// with path q
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features p q)
+		)
+	)
+	(path p 30.0 -83.01 30.0 -83.0 30.000001 -83.0 30.01 -83.0)
+	(path q 30.0 -83.0 29.99 -83.0)
+	`, "(config (snapTolerance 0.5 merge))")
+	near := vd.NearCoincidences()
+	expected := "path 'p' point 2 (30.000000,-83.000000) and " +
+		"path 'p' point 3 (30.000001,-83.000000) are 0.11 meters apart"
+	if len(near) != 1 || near[0].String() != expected {
+		T.Fatalf("expected '%s', got %v", expected, near)
+	}
+	p := vd.mapItems["p"].(*map_locationType).location
+	if p.latlongPair(2).samePoint(p.latlongPair(4)) {
+		T.Fatalf("expected the points of p to stay distinct, got %v", p)
+	}
+}
+
+
+func Test_snapToleranceErrors(T *testing.T) {
+	for _, test := range []struct {
+		config, expected string
+	}{
+		{"(snapTolerance 0)", "infile0:1: snap tolerance must be greater than zero"},
+		{"(snapTolerance 1 join)", "infile0:1: unknown snap mode 'join'; expected merge"},
+		{"(snapTolerance 1) (snapTolerance 2)", "infile0:1: snap tolerance already set"},
+	} {
+		prepareAndParseExpectingError(T, []io.Reader{
+			strings.NewReader("(config " + test.config + ")")}, test.expected)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"potano.misiones/great"
+)
+
+// Paths join only where their vertices coincide exactly.  A path which misses its neighbor by
+// a microdegree leads to a threading failure, so the crossing finder can be asked to report
+// vertices of different paths or waypoints which lie within a tolerance of one another and,
+// optionally, to move each cluster of such vertices to a single point.
+
+
+func (vd *VectorData) setSnapTolerance(item *mapSnapToleranceType) error {
+	if vd.snapToleranceSet {
+		return item.Error("snap tolerance already set")
+	}
+	if item.meters <= 0 {
+		return item.Error("snap tolerance must be greater than zero")
+	}
+	if len(item.mode) > 0 && item.mode != "merge" {
+		return item.Error("unknown snap mode '%s'; expected merge", item.mode)
+	}
+	vd.crossingFinder.snapTolerance = item.meters
+	vd.crossingFinder.snapMerge = item.mode == "merge"
+	vd.snapToleranceSet = true
+	return nil
+}
+
+
+// Overrides the snap tolerance, if any, given in the config section.  Must be called before
+// ResolveReferences.
+func (vd *VectorData) SetSnapTolerance(meters float64, merge bool) error {
+	if meters <= 0 {
+		return fmt.Errorf("snap tolerance must be greater than zero")
+	}
+	vd.crossingFinder.snapTolerance = meters
+	vd.crossingFinder.snapMerge = merge
+	vd.snapToleranceSet = true
+	return nil
+}
+
+
+
+// Pair of points found to be within the snap tolerance of one another
+type nearPointPair struct {
+	points [2]latlongType
+	locations [2]cpathInfo			// a location and offset at each point
+	meters float64
+	merged bool
+}
+
+type NearCoincidence struct {
+	Meters float64
+	Merged bool
+	Description string
+}
+
+func (nc NearCoincidence) String() string {
+	if nc.Merged {
+		return fmt.Sprintf("merged %s, %.2f meters apart", nc.Description, nc.Meters)
+	}
+	return fmt.Sprintf("%s are %.2f meters apart", nc.Description, nc.Meters)
+}
+
+// Lists the points found within the snap tolerance of one another
+func (vd *VectorData) NearCoincidences() []NearCoincidence {
+	cf := vd.crossingFinder
+	if len(cf.nearPoints) == 0 {
+		return nil
+	}
+	locations := vd.locationsByIndex()
+	describe := func (point latlongType, cpi cpathInfo) string {
+		loc := locations[cpi.locationIndex]
+		where := fmt.Sprintf("(%s,%s)", point.lat, point.long)
+		if loc == nil {
+			return where
+		}
+		if !loc.isPoint() {
+			where = fmt.Sprintf("point %d %s", cpi.offset / 2 + 1, where)
+		}
+		return fmt.Sprintf("%s '%s' %s", loc.ItemTypeString(), loc.Name(), where)
+	}
+	list := make([]NearCoincidence, len(cf.nearPoints))
+	for i, pair := range cf.nearPoints {
+		list[i] = NearCoincidence{
+			Meters: pair.meters,
+			Merged: pair.merged,
+			Description: describe(pair.points[0], pair.locations[0]) + " and " +
+				describe(pair.points[1], pair.locations[1]),
+		}
+	}
+	return list
+}
+
+
+
+// Paths, points, and other locations as declared, excluding the parts split from paths
+func (vd *VectorData) locationsByIndex() map[locationIndexType]*map_locationType {
+	locations := map[locationIndexType]*map_locationType{}
+	for _, item := range vd.mapItems {
+		if loc, is := item.(*map_locationType); is && loc.prototypePath == nil {
+			locations[loc.locIndex] = loc
+		}
+	}
+	return locations
+}
+
+
+
+// Finds the pairs of distinct points within the tolerance of one another.  Pairs of points
+// which belong to only the same single location are not of interest.
+func findNearPoints(allPoints map[latlongType][]cpathInfo, tolerance float64,
+		earth great.EarthModel) []nearPointPair {
+	points := make([]latlongType, 0, len(allPoints))
+	for point := range allPoints {
+		points = append(points, point)
+	}
+	sort.Slice(points, func (i, j int) bool {
+		return points[i].lat < points[j].lat ||
+			(points[i].lat == points[j].lat && points[i].long < points[j].long)
+	})
+	// Degrees of latitude are never shorter than 110 km
+	latWindow := locAngleType(math.Ceil(tolerance / 110000 / latLongFixedToFloatMultiplier))
+	var pairs []nearPointPair
+	for i, p1 := range points {
+		lat1 := float64(p1.lat) * latLongFixedToFloatMultiplier * great.DEG_TO_RADIANS
+		long1 := float64(p1.long) * latLongFixedToFloatMultiplier * great.DEG_TO_RADIANS
+		for _, p2 := range points[i+1:] {
+			if p2.lat - p1.lat > latWindow {
+				break
+			}
+			lat2 := float64(p2.lat) * latLongFixedToFloatMultiplier * great.DEG_TO_RADIANS
+			long2 := float64(p2.long) * latLongFixedToFloatMultiplier * great.DEG_TO_RADIANS
+			meters := earth.MetersBetweenPoints(lat1, long1, lat2, long2)
+			if meters > tolerance {
+				continue
+			}
+			loc1, only1 := soleLocation(allPoints[p1])
+			loc2, only2 := soleLocation(allPoints[p2])
+			if only1 && only2 && loc1.locationIndex == loc2.locationIndex {
+				continue
+			}
+			pairs = append(pairs, nearPointPair{[2]latlongType{p1, p2},
+				[2]cpathInfo{loc1, loc2}, meters, false})
+		}
+	}
+	return pairs
+}
+
+
+// Returns the first location at a point and whether it is the only location there
+func soleLocation(cpiList []cpathInfo) (cpathInfo, bool) {
+	var first cpathInfo
+	found, only := false, true
+	for _, cpi := range cpiList {
+		if cpi.locationIndex < 0 {
+			// Endpoint marker
+			continue
+		}
+		if !found {
+			first, found = cpi, true
+		} else if cpi.locationIndex != first.locationIndex {
+			only = false
+		}
+	}
+	return first, only
+}
+
+
+// Moves each cluster of near points to the member used by the most locations.  Points of the
+// same location are never merged, since that would collapse a step of a path.  Marks the pairs
+// merged and returns the substitutions made.
+func mergeNearPoints(allPoints map[latlongType][]cpathInfo,
+		pairs []nearPointPair) map[latlongType]latlongType {
+	parent := map[latlongType]latlongType{}
+	var find func (latlongType) latlongType
+	find = func (point latlongType) latlongType {
+		if up, exists := parent[point]; exists && up != point {
+			root := find(up)
+			parent[point] = root
+			return root
+		}
+		return point
+	}
+	prefer := func (a, b latlongType) bool {
+		na, nb := len(allPoints[a]), len(allPoints[b])
+		return na > nb || (na == nb && (a.lat < b.lat || (a.lat == b.lat && a.long < b.long)))
+	}
+	clusterLocations := map[latlongType]map[locationIndexType]bool{}
+	locationsOf := func (root latlongType) map[locationIndexType]bool {
+		indices, exists := clusterLocations[root]
+		if !exists {
+			indices = map[locationIndexType]bool{}
+			for _, cpi := range allPoints[root] {
+				if cpi.locationIndex >= 0 {
+					indices[cpi.locationIndex] = true
+				}
+			}
+			clusterLocations[root] = indices
+		}
+		return indices
+	}
+	for i, pair := range pairs {
+		root1, root2 := find(pair.points[0]), find(pair.points[1])
+		if root1 == root2 {
+			pairs[i].merged = true
+			continue
+		}
+		if prefer(root2, root1) {
+			root1, root2 = root2, root1
+		}
+		indices1, indices2 := locationsOf(root1), locationsOf(root2)
+		shared := false
+		for index := range indices2 {
+			shared = shared || indices1[index]
+		}
+		if shared {
+			continue
+		}
+		for index := range indices2 {
+			indices1[index] = true
+		}
+		parent[root2] = root1
+		pairs[i].merged = true
+	}
+	substitutions := map[latlongType]latlongType{}
+	for point := range parent {
+		if root := find(point); root != point {
+			substitutions[point] = root
+		}
+	}
+	for point, root := range substitutions {
+		allPoints[root] = append(allPoints[root], allPoints[point]...)
+		delete(allPoints, point)
+	}
+	return substitutions
+}
+
+
+// Moves the vertices of locations which were merged into near points
+func (vd *VectorData) applySnapSubstitutions() {
+	substitutions := vd.crossingFinder.substitutions
+	if len(substitutions) == 0 {
+		return
+	}
+	for _, item := range vd.mapItems {
+		loc, is := item.(*map_locationType)
+		if !is || !loc.isRouteComponent {
+			continue
+		}
+		for i := 0; i < len(loc.location); i += 2 {
+			if to, found := substitutions[loc.location.latlongPair(i)]; found {
+				loc.location[i], loc.location[i+1] = to.lat, to.long
+			}
+		}
+	}
+}
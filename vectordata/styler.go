@@ -144,7 +144,7 @@ func (sty *styler) serializeStyles(jsg jsGenerator) {
 		props := sty.referencedStyles[datum.rsIndex]
 		parts := make([]string, 0, len(props))
 		for k, v := range props {
-			parts = append(parts, "\"" + k + "\":" + v.jsonForm())
+			parts = append(parts, jsonQuote(k) + ":" + v.jsonForm())
 		}
 		sort.Strings(parts)
 		jsg.styles.addEntry("{" + strings.Join(parts, ",") + "}")
@@ -165,6 +165,19 @@ func (sty *styler) styleIndex(node mapItemType) int {
 	return sty.sortedStyleMap[val]
 }
 
+func (sty *styler) resolvedProperties(node mapItemType) cssPropertyMap {
+	if sty == nil {
+		return nil
+	}
+	style, attestation := node.styleAndAttestation()
+	if attestation != nil {
+		return sty.referencedStyles[attestation.resolvedStyleIndex]
+	} else if style != nil {
+		return sty.referencedStyles[style.resolvedStyleIndex]
+	}
+	return nil
+}
+
 
 
 
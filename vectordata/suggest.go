@@ -0,0 +1,367 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"potano.misiones/sexp"
+)
+
+// Where the threader cannot tell which of two places joins neighboring components, or which end
+// of a component at the start or end of a route is free, it chooses one and reports the
+// ambiguity.  A waypoint placed at the chosen point beside the component removes the
+// ambiguity, so each such case yields a suggested waypoint which may be written into the
+// source.
+
+const (
+	suggestStart = iota
+	suggestEnd
+	suggestJoin
+)
+
+type threadingSuggestion struct {
+	kind int
+	child threadableMapItemType
+	neighbor, parent mapItemType
+	point latlongType
+	alternatives []latlongType
+}
+
+type ThreadingSuggestion struct {
+	Source string
+	Waypoint string
+	Description string
+}
+
+func (ts ThreadingSuggestion) String() string {
+	return ts.Source + ": " + ts.Description
+}
+
+
+func (vd *VectorData) suggestWaypoint(kind int, child threadableMapItemType,
+		neighbor, parent mapItemType, point latlongType, candidates ...latlongType) {
+	suggestion := threadingSuggestion{kind, child, neighbor, parent, point, nil}
+	for _, candidate := range candidates {
+		if candidate.samePoint(point) {
+			continue
+		}
+		known := false
+		for _, alternative := range suggestion.alternatives {
+			known = known || alternative.samePoint(candidate)
+		}
+		if !known {
+			suggestion.alternatives = append(suggestion.alternatives, candidate)
+		}
+	}
+	vd.threadingSuggestions = append(vd.threadingSuggestions, suggestion)
+}
+
+
+// Lists the waypoints which would settle the ambiguities met in threading
+func (vd *VectorData) ThreadingSuggestions() []ThreadingSuggestion {
+	list := make([]ThreadingSuggestion, len(vd.threadingSuggestions))
+	for i, suggestion := range vd.threadingSuggestions {
+		list[i] = suggestion.export()
+	}
+	return list
+}
+
+
+func (ts threadingSuggestion) export() ThreadingSuggestion {
+	waypoint := waypointText(ts.point)
+	alternatives := make([]string, len(ts.alternatives))
+	for i, point := range ts.alternatives {
+		alternatives[i] = waypointText(point)
+	}
+	var description string
+	switch ts.kind {
+	case suggestStart:
+		description = fmt.Sprintf("add %s before %s in %s to start it there", waypoint,
+			describeComponent(ts.child), describeComponent(ts.parent))
+		if len(alternatives) > 0 {
+			description += "; " + strings.Join(alternatives, " or ") +
+				" would start it at its other end"
+		}
+	case suggestEnd:
+		description = fmt.Sprintf("add %s after %s in %s to end it there", waypoint,
+			describeComponent(ts.child), describeComponent(ts.parent))
+		if len(alternatives) > 0 {
+			description += "; " + strings.Join(alternatives, " or ") +
+				" would end it at its other end"
+		}
+	case suggestJoin:
+		description = fmt.Sprintf("add %s between %s and %s in %s to join them there",
+			waypoint, describeComponent(ts.neighbor), describeComponent(ts.child),
+			describeComponent(ts.parent))
+		if len(alternatives) > 0 {
+			description += "; " + strings.Join(alternatives, " or ") +
+				" would join them elsewhere"
+		}
+	}
+	return ThreadingSuggestion{
+		Source: ts.listedAt().SourceDescription(),
+		Waypoint: waypoint,
+		Description: description,
+	}
+}
+
+// The place in the source where the component is listed
+func (ts threadingSuggestion) listedAt() sexp.ValueSource {
+	if tmir, is := ts.child.(*threadableMapItemReference); is {
+		return tmir.source
+	}
+	return ts.child.Source()
+}
+
+func waypointText(point latlongType) string {
+	return fmt.Sprintf("(point %s %s)", point.lat, point.long)
+}
+
+
+
+// Writes the suggested waypoints into the source files.  The readSource function returns the
+// text of a source file.  Returns the rewritten files and the suggestions for which no place
+// could be found in the source text.
+func (vd *VectorData) FixThreading(readSource func(fileName string) (string, error),
+		) ([]GeneratedFile, []ThreadingSuggestion, error) {
+	var fileNames []string
+	byFile := map[string][]threadingSuggestion{}
+	var unfixed []ThreadingSuggestion
+	for _, suggestion := range vd.threadingSuggestions {
+		fileName := suggestion.listedAt().Filename()
+		if len(fileName) == 0 {
+			unfixed = append(unfixed, suggestion.export())
+			continue
+		}
+		if _, exists := byFile[fileName]; !exists {
+			fileNames = append(fileNames, fileName)
+		}
+		byFile[fileName] = append(byFile[fileName], suggestion)
+	}
+	var files []GeneratedFile
+	for _, fileName := range fileNames {
+		text, err := readSource(fileName)
+		if err != nil {
+			return nil, nil, err
+		}
+		var edits []sourceEdit
+		for _, suggestion := range byFile[fileName] {
+			edit, found := suggestion.findEdit(text)
+			if !found {
+				unfixed = append(unfixed, suggestion.export())
+				continue
+			}
+			edits = append(edits, edit)
+		}
+		if len(edits) > 0 {
+			files = append(files, GeneratedFile{fileName, applySourceEdits(text, edits)})
+		}
+	}
+	return files, unfixed, nil
+}
+
+
+type sourceEdit struct {
+	offset int
+	insert string
+}
+
+// Applies the edits, ignoring repeated ones
+func applySourceEdits(text string, edits []sourceEdit) string {
+	sort.SliceStable(edits, func (i, j int) bool {
+		return edits[i].offset > edits[j].offset
+	})
+	for i, edit := range edits {
+		if i > 0 && edit == edits[i-1] {
+			continue
+		}
+		text = text[:edit.offset] + edit.insert + text[edit.offset:]
+	}
+	return text
+}
+
+
+// Finds where in the source text to place the waypoint.  A component listed by name is found
+// by its name on the line of the reference; a component defined in place is found by the
+// opening of its list.
+func (ts threadingSuggestion) findEdit(text string) (sourceEdit, bool) {
+	waypoint := waypointText(ts.point)
+	before := ts.kind != suggestEnd
+	lineno := int(ts.listedAt().Lineno())
+	if _, is := ts.child.(*threadableMapItemReference); is {
+		return findReferenceEdit(text, lineno, ts.child.Name(), waypoint, before)
+	}
+	return findListEdit(text, lineno, ts.child.ItemTypeString(), exportableName(ts.child),
+		waypoint, before)
+}
+
+
+// Places the waypoint beside a name in a reference list such as (paths a b c), splitting the
+// list if the name is not at its start or end
+func findReferenceEdit(text string, lineno int, name, waypoint string,
+		before bool) (sourceEdit, bool) {
+	nameStart, found := findOnLine(text, lineno, name)
+	if !found {
+		return sourceEdit{}, false
+	}
+	nameEnd := nameStart + len(name)
+	open := strings.LastIndexByte(text[:nameStart], '(')
+	if open < 0 || strings.ContainsAny(text[open+1:nameStart], ");") {
+		return sourceEdit{}, false
+	}
+	fields := strings.Fields(text[open+1:nameStart])
+	if len(fields) == 0 {
+		return sourceEdit{}, false
+	}
+	head := fields[0]
+	afterName := strings.TrimLeft(text[nameEnd:], " \t\r\n")
+	if before {
+		if len(fields) == 1 {
+			return sourceEdit{open, waypoint + separatorAt(text, open)}, true
+		}
+		previousEnd := len(strings.TrimRight(text[:nameStart], " \t\r\n"))
+		return sourceEdit{previousEnd, ") " + waypoint + " (" + head}, true
+	}
+	if strings.HasPrefix(afterName, ";") {
+		return sourceEdit{}, false
+	}
+	if strings.HasPrefix(afterName, ")") {
+		close := len(text) - len(afterName)
+		return sourceEdit{close + 1, separatorAt(text, open) + waypoint}, true
+	}
+	return sourceEdit{nameEnd, ") " + waypoint + " (" + head}, true
+}
+
+
+// Places the waypoint before or after a list such as (path one ...) opened on the given line
+func findListEdit(text string, lineno int, head, name, waypoint string,
+		before bool) (sourceEdit, bool) {
+	start, end, found := lineBounds(text, lineno)
+	if !found {
+		return sourceEdit{}, false
+	}
+	open := -1
+	for pos := start; pos < end; pos++ {
+		if text[pos] == ';' {
+			break
+		}
+		if text[pos] != '(' {
+			continue
+		}
+		fields := strings.Fields(strings.ReplaceAll(text[pos+1:end], "(", " ( "))
+		if len(fields) == 0 || fields[0] != head ||
+				(len(name) > 0 && (len(fields) < 2 || fields[1] != name)) {
+			continue
+		}
+		if open >= 0 {
+			// More than one candidate on the line
+			return sourceEdit{}, false
+		}
+		open = pos
+	}
+	if open < 0 {
+		return sourceEdit{}, false
+	}
+	if before {
+		return sourceEdit{open, waypoint + separatorAt(text, open)}, true
+	}
+	close, found := matchingParen(text, open)
+	if !found {
+		return sourceEdit{}, false
+	}
+	return sourceEdit{close + 1, separatorAt(text, open) + waypoint}, true
+}
+
+
+// Separates an inserted waypoint from the list at the given offset:  a newline with the same
+// indentation if the list begins its line, otherwise a space
+func separatorAt(text string, offset int) string {
+	lineStart := strings.LastIndexByte(text[:offset], '\n') + 1
+	indentation := text[lineStart:offset]
+	if strings.TrimLeft(indentation, " \t") == "" {
+		return "\n" + indentation
+	}
+	return " "
+}
+
+
+func lineBounds(text string, lineno int) (int, int, bool) {
+	start := 0
+	for line := 1; line < lineno; line++ {
+		next := strings.IndexByte(text[start:], '\n')
+		if next < 0 {
+			return 0, 0, false
+		}
+		start += next + 1
+	}
+	end := strings.IndexByte(text[start:], '\n')
+	if end < 0 {
+		end = len(text)
+	} else {
+		end += start
+	}
+	return start, end, true
+}
+
+
+// Finds the single occurrence of a symbol on a line outside of any comment
+func findOnLine(text string, lineno int, symbol string) (int, bool) {
+	start, end, found := lineBounds(text, lineno)
+	if !found {
+		return 0, false
+	}
+	if comment := strings.IndexByte(text[start:end], ';'); comment >= 0 {
+		end = start + comment
+	}
+	isBreak := func (pos int) bool {
+		return pos < start || pos >= end || strings.IndexByte(" \t\r()", text[pos]) >= 0
+	}
+	position := -1
+	for pos := start; pos + len(symbol) <= end; pos++ {
+		if text[pos:pos+len(symbol)] != symbol || !isBreak(pos - 1) ||
+				!isBreak(pos + len(symbol)) {
+			continue
+		}
+		if position >= 0 {
+			return 0, false
+		}
+		position = pos
+	}
+	return position, position >= 0
+}
+
+
+// Finds the parenthesis closing the list opened at the given offset, skipping strings and
+// comments
+func matchingParen(text string, open int) (int, bool) {
+	depth := 0
+	for pos := open; pos < len(text); pos++ {
+		switch text[pos] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pos, true
+			}
+		case ';':
+			next := strings.IndexByte(text[pos:], '\n')
+			if next < 0 {
+				return 0, false
+			}
+			pos += next
+		case '"', '\'':
+			quote := text[pos]
+			for pos++; pos < len(text) && text[pos] != quote; pos++ {
+				if text[pos] == '\\' {
+					pos++
+				}
+			}
+		}
+	}
+	return 0, false
+}
@@ -13,11 +13,18 @@ func (vd *VectorData) threadRouteOrSegment(item *mapRouteOrSegmentType) error {
 	if nil != err {
 		return err
 	}
-	markedChildren, err := vd.markComponentIntersections(item, children)
+	explain := vd.explainerFor(item)
+	explain.begin(item, children)
+	errorCount := len(vd.deferredErrors)
+	markedChildren, err := vd.markComponentIntersections(item, children, explain)
 	if err != nil {
 		return err
 	}
 	pickedChildren := pickThreadedItems(item, markedChildren)
+	explain.explainPicks(children, markedChildren, pickedChildren)
+	vd.checkLoopClosure(pickedChildren)
+	vd.checkAlternatives(pickedChildren)
+	explain.explainErrors(vd.deferredErrors[errorCount:])
 	return vd.finishThreading(pickedChildren)
 }
 
@@ -71,12 +78,15 @@ func gatherThreadedItemList(item *mapRouteOrSegmentType) ([]threadableMapItemTyp
 
 
 func (vd *VectorData) markComponentIntersections(item threadableMapItemType,
-		children []threadableMapItemType) ([]pendingChildInfo, error) {
+		children []threadableMapItemType, explain *threadingExplainer) ([]pendingChildInfo,
+		error) {
 	// Augment each component item with a record that indicates how it relates to its neighbor
 	boundedChildren := make([]pendingChildInfo, len(children))
 	previousChildInfo := &boundedChildren[0]
 	var previousCrosspoints latlongRefs
 	endingCrosspoints := make([]latlongRefs, len(children))
+	ambiguousJoins := make([]latlongRefs, len(children))
+	isLoop := vd.loopOf(item) != nil
 	for childX, child := range children {
 		childInfo := &boundedChildren[childX]
 		childInfo.child = child
@@ -84,18 +94,33 @@ func (vd *VectorData) markComponentIntersections(item threadableMapItemType,
 		if childX > 0 {
 			matching, notMatching, ambiguous :=
 				childCrosspoints.findMatchingCrosspoints(previousCrosspoints)
+			// A loop of two components closes where they meet a second time
+			closesLoop := isLoop && len(children) == 2 && len(distinctPoints(
+				childCrosspoints.sharedWith(previousCrosspoints).points())) == 2
 			if len(matching) > 0 {
 				childInfo.startRefs = matching
-				if ambiguous {
+				explain.noteStart(childX, "meets %s at %s",
+					describeComponent(children[childX - 1]), describeRefs(child, matching))
+				if ambiguous && closesLoop {
+					explain.noteStart(childX, "the two components of the loop meet " +
+						"at both ends")
+				} else if ambiguous {
 					noteAmbiguousCrosspointMatch(vd, child,
 					children[childX - 1])
+					ambiguousJoins[childX] =
+						childCrosspoints.sharedWith(previousCrosspoints)
+					explain.noteStart(childX, "ambiguous: the components meet at " +
+						"more than one place")
 				}
 				matching, _, _ = previousChildInfo.child.getCrosspoints().
 					findMatchingCrosspoints(matching)
 				previousChildInfo.endRefs = matching
+				explain.noteEnd(childX - 1, "meets %s at %s", describeComponent(child),
+					describeRefs(previousChildInfo.child, matching))
 			}
 			if child.isPoint() {
 				childInfo.endRefs = childInfo.startRefs
+				explain.noteEnd(childX, "a point ends where it starts")
 				previousCrosspoints = matching
 			} else {
 				previousCrosspoints = notMatching
@@ -112,6 +137,7 @@ func (vd *VectorData) markComponentIntersections(item threadableMapItemType,
 		childInfo := &boundedChildren[childX]
 		startRefs, endRefs := childInfo.startRefs, childInfo.endRefs
 		child := childInfo.child
+		var ambiguousStart, ambiguousEnd latlongRefs
 		if len(startRefs) == 0 || len(endRefs) == 0 {
 			childEnds1, childEnds2 := synthesizeCrosspointReferences(child)
 			crosspointsAtEnd := endingCrosspoints[childX]
@@ -119,6 +145,8 @@ func (vd *VectorData) markComponentIntersections(item threadableMapItemType,
 			if len(startRefs) == 0 && len(endRefs) == 0 {
 				if len(boundedChildren) > 1 {
 					noteFailedCrosspointMatch(vd, child, item)
+					explain.noteStart(childX, "meets no neighbor; taking its first point")
+					explain.noteEnd(childX, "meets no neighbor; taking its last point")
 				}
 				childInfo.startRefs = childEnds1
 				childInfo.endRefs = childEnds2
@@ -131,14 +159,22 @@ func (vd *VectorData) markComponentIntersections(item threadableMapItemType,
 				if len(matching2) > 0 {
 					if len(matching1) > 0 {
 						noteAmbiguousEndpoint(vd, child, item)
+						explain.noteEnd(childX, "ambiguous: both of its ends are free")
+						ambiguousEnd = childEnds1
 					}
 					endRefs = matching2
 					availableForStartpoint = notMatching2
+					explain.noteEnd(childX, "nothing meets its end; taking its " +
+						"free last point")
 				} else if len(matching1) > 0 {
 					endRefs = matching1
 					availableForStartpoint = notMatching1
+					explain.noteEnd(childX, "nothing meets its end; taking its " +
+						"free first point")
 				} else {
 					endRefs = childEnds2
+					explain.noteEnd(childX, "nothing meets its end and neither " +
+						"end is free; taking its last point")
 				}
 			} else {
 				_, availableForStartpoint, _ = crosspointsAtEnd.
@@ -155,26 +191,50 @@ func (vd *VectorData) markComponentIntersections(item threadableMapItemType,
 				if len(matching1) > 0 {
 					if len(matching2) > 0 {
 						noteAmbiguousEndpoint(vd, child, item)
+						explain.noteStart(childX, "ambiguous: both of its ends are free")
+						ambiguousStart = childEnds2
 					}
 					startRefs = matching1
+					explain.noteStart(childX, "nothing meets its start; taking its free " +
+						"first point")
 				} else if len(matching2) > 0 {
 					startRefs = matching2
+					explain.noteStart(childX, "nothing meets its start; taking its free " +
+						"last point")
 				} else {
 					startRefs = childEnds1
+					explain.noteStart(childX, "nothing meets its start and neither " +
+						"end is free; taking its first point")
 				}
 			}
 		}
 		if len(startRefs) > 1 || len(endRefs) > 1 {
 			ascending := startRefs[0].comesBefore(endRefs[0])
 			if len(startRefs) > 1 {
+				explain.noteStart(childX, "%d candidates at the same place; preferring " +
+					"the %s path", len(startRefs), firstOrLast(ascending))
 				startRefs = resolveEndRefs(child, startRefs, ascending)
 			}
 			if len(endRefs) > 1 {
+				explain.noteEnd(childX, "%d candidates at the same place; preferring " +
+					"the %s path", len(endRefs), firstOrLast(!ascending))
 				endRefs = resolveEndRefs(child, endRefs, !ascending)
 			}
 		}
 		childInfo.startRefs = startRefs
 		childInfo.endRefs = endRefs
+		if len(ambiguousJoins[childX]) > 0 {
+			vd.suggestWaypoint(suggestJoin, child, children[childX - 1], item,
+				startRefs[0].point, ambiguousJoins[childX].points()...)
+		}
+		if len(ambiguousStart) > 0 {
+			vd.suggestWaypoint(suggestStart, child, nil, item, startRefs[0].point,
+				ambiguousStart.points()...)
+		}
+		if len(ambiguousEnd) > 0 {
+			vd.suggestWaypoint(suggestEnd, child, nil, item, endRefs[0].point,
+				ambiguousEnd.points()...)
+		}
 	}
 	for childX, child := range boundedChildren {
 		if item, is := child.child.(*threadableMapItemReference); is {
@@ -65,6 +65,10 @@ func (ti *threadableMapItemReference) setAttestation(attestation *mapAttestation
 	ti.item.setAttestation(attestation)
 }
 
+func (ti *threadableMapItemReference) setPeriod(period *mapPeriodType) {
+	ti.item.setPeriod(period)
+}
+
 func (ti *threadableMapItemReference) setHtml(html *map_textType) {
 	ti.item.setHtml(html)
 }
@@ -0,0 +1,194 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"fmt"
+	"strconv"
+
+	"potano.misiones/sexp"
+)
+
+
+// Travel rate declared by a travelRate configuration item or by the travel attribute of a
+// route.  A route's travel attribute may instead name a configured rate.
+type mapTravelRateType struct {
+	mapItemCore
+	amount float64
+	symbols []string
+}
+
+func newMapTravelRate(doc *VectorData, parent mapItemType, listType, listName string,
+		source sexp.ValueSource) (mapItemType, error) {
+	mt := &mapTravelRateType{}
+	mt.source = source
+	mt.name = listName
+	mt.itemType = nameToTypeMap[listType]
+	if route, is := parent.(*mapRouteOrSegmentType); is {
+		mt.name = route.Name()
+		route.travel = mt
+	}
+	return mt, nil
+}
+
+func (mt *mapTravelRateType) addScalars(targetName string, scalars []sexp.LispScalar) error {
+	switch targetName {
+	case "amount":
+		amount, err := strconv.ParseFloat(scalars[0].String(), 64)
+		if err != nil {
+			return mt.Error("%s", err)
+		}
+		if amount <= 0 {
+			return mt.Error("travel rate must be greater than zero")
+		}
+		mt.amount = amount
+	case "unitAndPeriod":
+		for _, scalar := range scalars {
+			mt.symbols = append(mt.symbols, scalar.String())
+		}
+	}
+	return nil
+}
+
+
+var travelPeriods = map[string]string{
+	"perDay": "days",
+	"perHour": "hours",
+}
+
+func (vd *VectorData) setTravelRate(item *mapTravelRateType) error {
+	if _, exists := vd.travelRates[item.name]; exists {
+		return item.Error("travel rate %s already set", item.name)
+	}
+	if len(item.symbols) != 2 {
+		return item.Error("travel rate must give a unit and a period")
+	}
+	if _, valid := travelPeriods[item.symbols[1]]; !valid {
+		return item.Error("travel period must be perDay or perHour")
+	}
+	vd.travelRates[item.name] = item
+	return nil
+}
+
+
+// Returns the meters per period and the name of the period in the plural
+func (vd *VectorData) resolveTravelRate(item *mapTravelRateType) (float64, string, error) {
+	if item.amount == 0 {
+		// Reference to a configured rate
+		if len(item.symbols) != 1 {
+			return 0, "", item.Error("travel must name a travel rate or give a rate")
+		}
+		rate, exists := vd.travelRates[item.symbols[0]]
+		if !exists {
+			return 0, "", item.Error("unknown travel rate '%s'", item.symbols[0])
+		}
+		item = rate
+	}
+	if len(item.symbols) != 2 {
+		return 0, "", item.Error("travel rate must give a unit and a period")
+	}
+	period, valid := travelPeriods[item.symbols[1]]
+	if !valid {
+		return 0, "", item.Error("travel period must be perDay or perHour")
+	}
+	metersPerUnit, exists := vd.lengthUnits[item.symbols[0]]
+	if !exists {
+		return 0, "", item.Error("measurement unit '%s' is undefined", item.symbols[0])
+	}
+	return item.amount * metersPerUnit, period, nil
+}
+
+
+
+type travelLeg struct {
+	Name string
+	Meters, Time float64
+}
+
+type travelEstimate struct {
+	Route, Period string		// period is "days" or "hours"
+	Legs []travelLeg		// each segment or path of the route
+	Meters, Time float64
+}
+
+// Estimates the time to travel each component of a route and the route as a whole.  Uses the
+// named travel rate or, if no name is given, the route's own travel attribute.
+func (vd *VectorData) EstimateTravel(routeName, rateName string) (travelEstimate, error) {
+	estimate := travelEstimate{Route: routeName}
+	item, exists := vd.mapItems[routeName]
+	if !exists {
+		return estimate, fmt.Errorf("unknown map item '%s'", routeName)
+	}
+	route, is := item.(*mapRouteOrSegmentType)
+	if !is {
+		return estimate, fmt.Errorf("%s %s is not a route or segment", item.ItemTypeString(),
+			routeName)
+	}
+	rate := route.travel
+	if len(rateName) > 0 {
+		rate, exists = vd.travelRates[rateName]
+		if !exists {
+			return estimate, fmt.Errorf("unknown travel rate '%s'", rateName)
+		}
+	} else if rate == nil {
+		return estimate, fmt.Errorf("%s %s has no travel rate", route.ItemTypeString(),
+			routeName)
+	}
+	metersPerPeriod, period, err := vd.resolveTravelRate(rate)
+	if err != nil {
+		return estimate, err
+	}
+	estimate.Period = period
+	measurer := &legMeasurer{route: route}
+	measurer.earth = vd.earth
+	err = walkPathsForItem(measurer, route, false)
+	if err != nil {
+		return estimate, err
+	}
+	for _, leg := range measurer.legs {
+		leg.Time = leg.Meters / metersPerPeriod
+		estimate.Legs = append(estimate.Legs, leg)
+		estimate.Meters += leg.Meters
+	}
+	estimate.Time = estimate.Meters / metersPerPeriod
+	return estimate, nil
+}
+
+
+// Measures the length of each top-level component of a route
+type legMeasurer struct {
+	simplePathMeasurer
+	route *mapRouteOrSegmentType
+	legs []travelLeg
+}
+
+func (lm *legMeasurer) beginComponent(parent *mapRouteOrSegmentType, child mapItemType) {
+	if parent != lm.route {
+		return
+	}
+	if loc, is := child.(*map_locationType); is && loc.isPoint() {
+		// Waypoints add no distance
+		return
+	}
+	name := exportableName(child)
+	if len(name) == 0 {
+		name = fmt.Sprintf("%s %d", child.ItemTypeString(), len(lm.legs) + 1)
+	}
+	lm.legs = append(lm.legs, travelLeg{Name: name})
+	lm.meters = 0
+}
+
+func (lm *legMeasurer) measurePath(path *map_locationType,
+		startOffset, endOffset locationIndexType) bool {
+	if path.isPoint() {
+		return true
+	}
+	lm.simplePathMeasurer.measurePath(path, startOffset, endOffset)
+	if len(lm.legs) == 0 {
+		// Walking a path directly
+		lm.legs = append(lm.legs, travelLeg{Name: path.Name()})
+	}
+	lm.legs[len(lm.legs) - 1].Meters = lm.meters
+	return true
+}
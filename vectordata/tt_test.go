@@ -0,0 +1,142 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+
+const travelConfig = `(config
+		(lengthUnit leagues 3 miles)
+		(travelRate foot 5 leagues perDay)
+		(travelRate horse 4 miles perHour)
+	)`
+
+
+func Test_travelEstimate(T *testing.T) {
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(travel foot)
+		(segment first (paths path1))
+		(segment second (paths path2))
+	)
+	` + path1 + path2, travelConfig)
+	estimate, err := vd.EstimateTravel("theRoad", "")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if estimate.Period != "days" {
+		T.Fatalf("expected period in days, got %s", estimate.Period)
+	}
+	if len(estimate.Legs) != 2 {
+		T.Fatalf("expected 2 legs, got %d", len(estimate.Legs))
+	}
+	metersPerDay := 5 * 3 * 1609.344
+	compareTestLengths(T, "first leg", estimate.Legs[0].Meters, path1_length)
+	compareTestLengths(T, "second leg", estimate.Legs[1].Meters, path2_length)
+	compareTestLengths(T, "route", estimate.Meters, path1_length + path2_length)
+	if estimate.Legs[0].Name != "first" || estimate.Legs[1].Name != "second" {
+		T.Fatalf("expected legs first and second, got %s and %s", estimate.Legs[0].Name,
+			estimate.Legs[1].Name)
+	}
+	compareTestLengths(T, "first leg days", estimate.Legs[0].Time * metersPerDay, path1_length)
+	compareTestLengths(T, "route days", estimate.Time * metersPerDay,
+		path1_length + path2_length)
+
+	estimate, err = vd.EstimateTravel("theRoad", "horse")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	if estimate.Period != "hours" {
+		T.Fatalf("expected period in hours, got %s", estimate.Period)
+	}
+	compareTestLengths(T, "route hours", estimate.Time * 4 * 1609.344,
+		path1_length + path2_length)
+}
+
+
+func Test_travelInlineRate(T *testing.T) {
+	vd := prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(travel 20 miles perDay)
+		(segment first (paths path1))
+		(segment second (paths path2))
+	)
+	` + path1 + path2)
+	estimate, err := vd.EstimateTravel("theRoad", "")
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	compareTestLengths(T, "route days", estimate.Time * 20 * 1609.344,
+		path1_length + path2_length)
+
+	vd = prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(segment first (paths path1))
+		(segment second (paths path2))
+	)
+	` + path1 + path2)
+	_, err = vd.EstimateTravel("theRoad", "")
+	checkTravelError(T, err, "route theRoad has no travel rate")
+	_, err = vd.EstimateTravel("theRoad", "mule")
+	checkTravelError(T, err, "unknown travel rate 'mule'")
+
+	vd = prepareAndParseStrings(T, `(layers
+		(layer one
+			(menuitem "Look")
+			(features theRoad)
+		)
+	)
+	(route theRoad
+		(travel 20 leagues perDay)
+		(segment first (paths path1))
+		(segment second (paths path2))
+	)
+	` + path1 + path2)
+	_, err = vd.EstimateTravel("theRoad", "")
+	checkTravelError(T, err, "measurement unit 'leagues' is undefined")
+}
+
+
+func Test_travelRateErrors(T *testing.T) {
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(`(config
+		(travelRate foot 5 miles perWeek))`)},
+		"infile0:2: travel period must be perDay or perHour")
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(`(config
+		(travelRate foot 5 miles perDay)
+		(travelRate foot 6 miles perDay))`)},
+		"infile0:3: travel rate foot already set")
+	prepareAndParseExpectingError(T, []io.Reader{strings.NewReader(`(config
+		(travelRate foot 0 miles perDay))`)},
+		"infile0:2: travel rate must be greater than zero")
+}
+
+
+func checkTravelError(T *testing.T, err error, expected string) {
+	T.Helper()
+	if err == nil {
+		T.Fatalf("expected error '%s'", expected)
+	}
+	if !strings.HasSuffix(err.Error(), expected) {
+		T.Fatalf("expected error '%s', got '%s'", expected, err.Error())
+	}
+}
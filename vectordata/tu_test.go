@@ -300,7 +300,7 @@ func (mis mapItemSynthesizer) thread_up_to_markComponentIntersections(item *mapR
 	if err != nil {
 		mis.T.Fatalf("gatherThreadedItemList: %s", err)
 	}
-	markedChildren, err := mis.vd.markComponentIntersections(item, threadableChildren)
+	markedChildren, err := mis.vd.markComponentIntersections(item, threadableChildren, nil)
 	if err != nil {
 		mis.T.Fatalf("markComponentIntersections: %s", err)
 	}
@@ -37,3 +37,35 @@ func (vd *VectorData) setLengthUnit(item *mapLengthUnitType) error {
 	return nil
 }
 
+
+
+func (vd *VectorData) setEarthModel(item *mapEarthModelType) error {
+	if vd.earthModelSet {
+		return item.Error("earth model already set")
+	}
+	model, err := great.NewEarthModel(item.modelName, item.parameters)
+	if err != nil {
+		return item.Error("%s", err)
+	}
+	vd.earth = model
+	vd.earthModelSet = true
+	return nil
+}
+
+
+// Overrides the earth model, if any, given in the config section
+func (vd *VectorData) SetEarthModel(name string, parameters []float64) error {
+	model, err := great.NewEarthModel(name, parameters)
+	if err != nil {
+		return err
+	}
+	vd.earth = model
+	vd.earthModelSet = true
+	return nil
+}
+
+
+// Describes the earth model in use, e.g. "wgs84" or "sphere 6.371e+06"
+func (vd *VectorData) EarthModel() string {
+	return vd.earth.String()
+}
@@ -6,6 +6,7 @@ package vectordata
 import (
 	"fmt"
 	"sort"
+	"potano.misiones/great"
 	"potano.misiones/sexp"
 )
 
@@ -18,9 +19,19 @@ type VectorData struct {
 	styler *styler
 	attester *attester
 	lengthUnits map[string]float64
+	geojsonKeys map[string]string
+	earth great.EarthModel
+	earthModelSet bool
 	crossingFinder *crossingFinderType
 	deferredErrors []error
 	routesToMeasure []*mapLengthRangeType
+	milepostRoutes []*mapMilepostsType
+	travelRates map[string]*mapTravelRateType
+	snapToleranceSet bool
+	crossingCheckSet bool
+	threadingExplainer *threadingExplainer
+	threadingSuggestions []threadingSuggestion
+	activeYears *mapPeriodType
 }
 
 type mapItemType interface {
@@ -35,6 +46,7 @@ type mapItemType interface {
 	setPopup(popup *mapPopupType)
 	setStyle(style *mapStyleType)
 	setAttestation(attestation *mapAttestationType)
+	setPeriod(period *mapPeriodType)
 	setHtml(html *map_textType)
 	setRadius(radius *mapRadiusType)
 	addFeature(feature mapItemType)
@@ -48,6 +60,9 @@ func NewVectorData() *VectorData {
 	return &VectorData{
 		mapItems: map[string]mapItemType{},
 		lengthUnits: initialLengthUnitMap(),
+		geojsonKeys: initialGeojsonKeyMap(),
+		travelRates: map[string]*mapTravelRateType{},
+		earth: great.NorthFloridaSphere,
 		crossingFinder: newCrossingFinder(),
 	}
 }
@@ -74,7 +89,7 @@ func (vd *VectorData) ResolveReferences() error {
 			return err
 		}
 	}
-	vd.crossingFinder.signalNoMoreInput()
+	vd.crossingFinder.signalNoMoreInput(vd.earth)
 
 	// The parser constructs a DAG from the root element plus zero or more disconnected
 	// segments that are also acyclic.  The target-resolution step above aims to join the
@@ -182,6 +197,8 @@ func (vd *VectorData) CheckInStylesAndAttestations() error {
 
 func (vd *VectorData) CheckAndReformRoutes() error {
 	allCrosspoints := vd.crossingFinder.getAllCrosspoints()
+	vd.applyInsertedVertices()
+	vd.applySnapSubstitutions()
 	for _, name := range vd.inDependencyOrder {
 		obj := vd.mapItems[name]
 		var err error
@@ -195,7 +212,11 @@ func (vd *VectorData) CheckAndReformRoutes() error {
 			return err
 		}
 	}
-	return nil
+	if len(vd.deferredErrors) > 0 {
+		// Routes may be broken
+		return nil
+	}
+	return vd.placeMileposts()
 }
 
 
@@ -0,0 +1,92 @@
+// Copyright © 2024 Michael Thompson
+// SPDX-License-Identifier: GPL-2.0-or-later
+
+package vectordata
+
+import (
+	"strings"
+	"testing"
+)
+
+
+// Path b runs backward from the end of the route to where it meets path a
+const reversedPathRoute = `(layers
+		(layer one
+			(menuitem "Look")
+			(features road)
+		)
+	)
+	(route road
+		(segment
+			(paths a b)
+		)
+	)
+	(path a 30.0 -83.0 30.0 -83.01)
+	(path b 30.01 -83.01 30.0 -83.01)
+	`
+
+
+func explainThreading(T *testing.T, name string, sourceList ...string) (string, error) {
+	T.Helper()
+	vd := prepareAndParseStringsOnly(T, sourceList...)
+	vd.SetExplainThreading(name)
+	err := vd.CheckAndReformRoutes()
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	return vd.ThreadingExplanation()
+}
+
+
+func Test_explainThreading(T *testing.T) {
+	text, err := explainThreading(T, "road", reversedPathRoute)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	for _, expected := range []string{
+		"threading anonymous segment (infile0:8)\n" +
+			"1. path 'a' (infile0:12)\n" +
+			"   candidate crosspoints: (30.000000,-83.000000), point 1 of path 'a'; " +
+			"(30.000000,-83.010000), point 2 of path 'a'\n" +
+			"   start: nothing meets its start; taking its free first point\n",
+		"   end: meets path 'b' at (30.000000,-83.010000), point 2 of path 'a'\n",
+		"2. path 'b' (infile0:13)\n",
+		"   start: meets path 'a' at (30.000000,-83.010000), point 2 of path 'b'\n" +
+			"   chose start (30.000000,-83.010000), point 2 of path 'b'\n" +
+			"   end: nothing meets its end; taking its free first point\n" +
+			"   chose end (30.010000,-83.010000), point 1 of path 'b'\n" +
+			"   reversed: its chosen end comes before its chosen start in its own order\n" +
+			"   offsets 2 to 0, from (30.000000,-83.010000) to (30.010000,-83.010000)\n",
+		"anonymous segment runs from (30.000000,-83.000000) to (30.010000,-83.010000)\n\n" +
+			"threading route 'road' (infile0:7)\n",
+		"route 'road' runs from (30.000000,-83.000000) to (30.010000,-83.010000)\n",
+	} {
+		if !strings.Contains(text, expected) {
+			T.Fatalf("expected\n%s\nin\n%s", expected, text)
+		}
+	}
+
+	text, err = explainThreading(T, "road", nearMissRoute)
+	if err != nil {
+		T.Fatal(err.Error())
+	}
+	expected := "error: infile0:9: path second does not connect with segment $3"
+	if !strings.Contains(text, expected) {
+		T.Fatalf("expected\n%s\nin\n%s", expected, text)
+	}
+}
+
+
+func Test_explainThreadingErrors(T *testing.T) {
+	for _, test := range []struct {
+		name, expected string
+	}{
+		{"nothing", "unknown map item 'nothing'"},
+		{"a", "path 'a' is not a route or segment"},
+	} {
+		_, err := explainThreading(T, test.name, reversedPathRoute)
+		if err == nil || err.Error() != test.expected {
+			T.Fatalf("expected error '%s', got %v", test.expected, err)
+		}
+	}
+}